@@ -0,0 +1,500 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	defaultConfigFile = "config.json"
+	defaultDSN        = "root:@/rentcar?charset=utf8mb4&parseTime=True&loc=Local"
+	// defaultMFAEncryptionKey lets development start without a key, it is
+	// public and refused in production
+	defaultMFAEncryptionKey = "cmVudGF1dG8tZGV2ZWxvcG1lbnQtbWZhLWtleS0zMmI="
+)
+
+type Config struct {
+	Env      string         `json:"env"`
+	Server   ServerConfig   `json:"server"`
+	Database DatabaseConfig `json:"database"`
+	Auth     AuthConfig     `json:"auth"`
+	Upload   UploadConfig   `json:"upload"`
+	Assets   AssetsConfig   `json:"assets"`
+	Mail     MailConfig     `json:"mail"`
+}
+
+type ServerConfig struct {
+	ListenAddr  string   `json:"listen_addr"`
+	CORSOrigins []string `json:"cors_origins"`
+	// PublicURL is where users reach the frontend, links in emails point there
+	PublicURL string `json:"public_url"`
+}
+
+type DatabaseConfig struct {
+	DSN             string   `json:"dsn"`
+	MaxOpenConns    int      `json:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime"`
+	LogQueries      bool     `json:"log_queries"`
+	// SeedDemoUsers creates demo accounts with known passwords, development only
+	SeedDemoUsers bool `json:"seed_demo_users"`
+}
+
+type AuthConfig struct {
+	SigningAlgorithm    string   `json:"signing_algorithm"`
+	AllowedAlgorithms   []string `json:"allowed_algorithms"`
+	KeyRotationInterval Duration `json:"key_rotation_interval"`
+	AccessTokenTTL      Duration `json:"access_token_ttl"`
+	MFAChallengeTTL     Duration `json:"mfa_challenge_ttl"`
+	MFAIssuer           string   `json:"mfa_issuer"`
+	RequireAdminMFA     bool     `json:"require_admin_mfa"`
+	// MFAEncryptionKey is the base64 encoded 32 byte AES key the TOTP secrets
+	// are stored with, generate one with: openssl rand -base64 32
+	MFAEncryptionKey string `json:"mfa_encryption_key"`
+	// ImpersonationTTL is how long a support impersonation token lasts
+	ImpersonationTTL Duration `json:"impersonation_ttl"`
+	// InvitationTTL is how long an emailed invitation link can be used
+	InvitationTTL Duration `json:"invitation_ttl"`
+	// RoleCacheTTL bounds how long role and user permission changes made by
+	// another instance take to apply, zero keeps them until they change locally
+	RoleCacheTTL Duration `json:"role_cache_ttl"`
+
+	Password      PasswordPolicyConfig `json:"password"`
+	OIDCProviders []OIDCProviderConfig `json:"oidc_providers"`
+}
+
+// PasswordPolicyConfig is enforced whenever a password is set. HistorySize is
+// the number of previous passwords that may not be reused.
+type PasswordPolicyConfig struct {
+	MinLength     int  `json:"min_length"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	HistorySize   int  `json:"history_size"`
+}
+
+// OIDCProviderConfig describes an OpenID Connect identity provider. Any issuer
+// exposing a discovery document works, including a local mock issuer.
+type OIDCProviderConfig struct {
+	Name         string   `json:"name"`
+	IssuerURL    string   `json:"issuer_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+}
+
+// UploadConfig holds the defaults of the upload settings, admins can change
+// the limits at runtime through /admin/settings
+type UploadConfig struct {
+	MaxFileSize      int64    `json:"max_file_size"`
+	AllowedMimeTypes []string `json:"allowed_mime_types"`
+}
+
+type AssetsConfig struct {
+	Dir    string `json:"dir"`
+	URL    string `json:"url"`
+	CarDir string `json:"car_dir"`
+}
+
+// MailConfig is the SMTP server used for outgoing email. Without a host the
+// emails are written to the log instead, which is enough for development.
+type MailConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+// Duration accepts Go duration strings such as "24h" in the config file
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("duration must be a string like \"15m\": %v", err)
+	}
+
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Defaults returns the settings used for local development
+func Defaults() *Config {
+	return &Config{
+		Env: EnvDevelopment,
+		Server: ServerConfig{
+			ListenAddr:  "0.0.0.0:3000",
+			CORSOrigins: []string{"*"},
+			PublicURL:   "http://localhost:5173",
+		},
+		Database: DatabaseConfig{
+			DSN:             defaultDSN,
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration{30 * time.Minute},
+			LogQueries:      true,
+		},
+		Auth: AuthConfig{
+			SigningAlgorithm:    "HS256",
+			AllowedAlgorithms:   []string{"HS256"},
+			KeyRotationInterval: Duration{30 * 24 * time.Hour},
+			AccessTokenTTL:      Duration{24 * time.Hour},
+			MFAChallengeTTL:     Duration{5 * time.Minute},
+			ImpersonationTTL:    Duration{30 * time.Minute},
+			InvitationTTL:       Duration{72 * time.Hour},
+			MFAIssuer:           "RentAuto",
+			MFAEncryptionKey:    defaultMFAEncryptionKey,
+			RoleCacheTTL:        Duration{5 * time.Minute},
+			Password: PasswordPolicyConfig{
+				MinLength:    10,
+				RequireUpper: true,
+				RequireLower: true,
+				RequireDigit: true,
+				HistorySize:  5,
+			},
+		},
+		Upload: UploadConfig{
+			MaxFileSize:      5 * 1024 * 1024, // 5MB
+			AllowedMimeTypes: []string{"image/jpeg", "image/png", "image/jpg"},
+		},
+		Assets: AssetsConfig{
+			Dir:    "./assets",
+			URL:    "/assets",
+			CarDir: "assets/car",
+		},
+		Mail: MailConfig{
+			Port: 587,
+			From: "RentAuto <no-reply@localhost>",
+		},
+	}
+}
+
+// Load builds the configuration from defaults, an optional JSON file and the
+// environment, in that order of precedence, and validates the result.
+func Load() (*Config, error) {
+	cfg := Defaults()
+
+	if err := cfg.loadFile(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// IsProduction reports whether the server runs in production mode
+func (c *Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// Validate checks the settings and refuses insecure defaults in production
+func (c *Config) Validate() error {
+	var problems []string
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		problems = append(problems, fmt.Sprintf("env must be %q or %q", EnvDevelopment, EnvProduction))
+	}
+	if c.Server.ListenAddr == "" {
+		problems = append(problems, "server.listen_addr is required")
+	}
+	if len(c.Server.CORSOrigins) == 0 {
+		problems = append(problems, "server.cors_origins must contain at least one origin")
+	}
+	if c.Server.PublicURL == "" {
+		problems = append(problems, "server.public_url is required")
+	}
+	if c.Database.DSN == "" {
+		problems = append(problems, "database.dsn is required")
+	}
+	if c.Database.MaxOpenConns < 1 {
+		problems = append(problems, "database.max_open_conns must be at least 1")
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problems = append(problems, "database.max_idle_conns must be between 0 and max_open_conns")
+	}
+	if !isSupportedAlgorithm(c.Auth.SigningAlgorithm) {
+		problems = append(problems, "auth.signing_algorithm must be one of HS256, RS256 or EdDSA")
+	}
+	if !contains(c.Auth.AllowedAlgorithms, c.Auth.SigningAlgorithm) {
+		problems = append(problems, "auth.allowed_algorithms must include auth.signing_algorithm")
+	}
+	for _, alg := range c.Auth.AllowedAlgorithms {
+		if !isSupportedAlgorithm(alg) {
+			problems = append(problems, fmt.Sprintf("auth.allowed_algorithms contains unsupported algorithm %q", alg))
+		}
+	}
+	if c.Auth.KeyRotationInterval.Duration < 0 {
+		problems = append(problems, "auth.key_rotation_interval must not be negative")
+	}
+	if c.Auth.AccessTokenTTL.Duration <= 0 {
+		problems = append(problems, "auth.access_token_ttl must be positive")
+	}
+	if c.Auth.MFAChallengeTTL.Duration <= 0 {
+		problems = append(problems, "auth.mfa_challenge_ttl must be positive")
+	}
+	if key, err := base64.StdEncoding.DecodeString(c.Auth.MFAEncryptionKey); err != nil || len(key) != 32 {
+		problems = append(problems, "auth.mfa_encryption_key must be 32 bytes encoded as base64")
+	}
+	if c.Auth.ImpersonationTTL.Duration <= 0 || c.Auth.ImpersonationTTL.Duration > c.Auth.AccessTokenTTL.Duration {
+		problems = append(problems, "auth.impersonation_ttl must be positive and at most auth.access_token_ttl")
+	}
+	if c.Auth.InvitationTTL.Duration <= 0 {
+		problems = append(problems, "auth.invitation_ttl must be positive")
+	}
+	if c.Auth.RoleCacheTTL.Duration < 0 {
+		problems = append(problems, "auth.role_cache_ttl must not be negative")
+	}
+	if c.Auth.Password.MinLength < 8 {
+		problems = append(problems, "auth.password.min_length must be at least 8")
+	}
+	if c.Auth.Password.HistorySize < 0 {
+		problems = append(problems, "auth.password.history_size must not be negative")
+	}
+	seenProviders := map[string]bool{}
+	for i, provider := range c.Auth.OIDCProviders {
+		prefix := fmt.Sprintf("auth.oidc_providers[%d]", i)
+		if provider.Name == "" || provider.IssuerURL == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			problems = append(problems, prefix+" needs name, issuer_url, client_id and redirect_url")
+		}
+		if seenProviders[provider.Name] {
+			problems = append(problems, fmt.Sprintf("%s duplicates provider name %q", prefix, provider.Name))
+		}
+		seenProviders[provider.Name] = true
+		if c.IsProduction() && !strings.HasPrefix(provider.IssuerURL, "https://") {
+			problems = append(problems, prefix+".issuer_url must use https in production")
+		}
+	}
+	if c.Upload.MaxFileSize <= 0 {
+		problems = append(problems, "upload.max_file_size must be positive")
+	}
+	if len(c.Upload.AllowedMimeTypes) == 0 {
+		problems = append(problems, "upload.allowed_mime_types must not be empty")
+	}
+	if c.Assets.Dir == "" || c.Assets.CarDir == "" || c.Assets.URL == "" {
+		problems = append(problems, "assets.dir, assets.url and assets.car_dir are required")
+	}
+	if c.Mail.Host != "" && (c.Mail.Port < 1 || c.Mail.From == "") {
+		problems = append(problems, "mail.port and mail.from are required when mail.host is set")
+	}
+
+	if c.IsProduction() {
+		if c.Database.SeedDemoUsers {
+			problems = append(problems, "database.seed_demo_users is only allowed in development")
+		}
+		if c.Database.DSN == defaultDSN {
+			problems = append(problems, "database.dsn still uses the development default")
+		}
+		if c.Auth.MFAEncryptionKey == defaultMFAEncryptionKey {
+			problems = append(problems, "auth.mfa_encryption_key still uses the development default")
+		}
+		for _, origin := range c.Server.CORSOrigins {
+			if origin == "*" {
+				problems = append(problems, "server.cors_origins must not allow every origin in production")
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	return nil
+}
+
+func (c *Config) loadFile() error {
+	path := os.Getenv("CONFIG_FILE")
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// The default file is optional, an explicitly named one is not
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	var errs []string
+
+	setString := func(key string, dest *string) {
+		if value, ok := os.LookupEnv(key); ok {
+			*dest = value
+		}
+	}
+
+	setList := func(key string, dest *[]string) {
+		if value, ok := os.LookupEnv(key); ok {
+			*dest = splitList(value)
+		}
+	}
+
+	setInt := func(key string, dest *int) {
+		if value, ok := os.LookupEnv(key); ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s must be an integer", key))
+				return
+			}
+			*dest = parsed
+		}
+	}
+
+	setInt64 := func(key string, dest *int64) {
+		if value, ok := os.LookupEnv(key); ok {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s must be an integer", key))
+				return
+			}
+			*dest = parsed
+		}
+	}
+
+	setBool := func(key string, dest *bool) {
+		if value, ok := os.LookupEnv(key); ok {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s must be a boolean", key))
+				return
+			}
+			*dest = parsed
+		}
+	}
+
+	setDuration := func(key string, dest *Duration) {
+		if value, ok := os.LookupEnv(key); ok {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s must be a duration such as 15m", key))
+				return
+			}
+			dest.Duration = parsed
+		}
+	}
+
+	setString("APP_ENV", &c.Env)
+
+	setString("LISTEN_ADDR", &c.Server.ListenAddr)
+	setList("CORS_ORIGINS", &c.Server.CORSOrigins)
+	setString("PUBLIC_URL", &c.Server.PublicURL)
+
+	setString("DB_DSN", &c.Database.DSN)
+	setInt("DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns)
+	setInt("DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns)
+	setDuration("DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime)
+	setBool("DB_LOG_QUERIES", &c.Database.LogQueries)
+	setBool("DB_SEED_DEMO_USERS", &c.Database.SeedDemoUsers)
+
+	setString("JWT_SIGNING_ALGORITHM", &c.Auth.SigningAlgorithm)
+	setList("JWT_ALLOWED_ALGORITHMS", &c.Auth.AllowedAlgorithms)
+	setDuration("JWT_KEY_ROTATION_INTERVAL", &c.Auth.KeyRotationInterval)
+	setDuration("ACCESS_TOKEN_TTL", &c.Auth.AccessTokenTTL)
+	setDuration("MFA_CHALLENGE_TTL", &c.Auth.MFAChallengeTTL)
+	setDuration("IMPERSONATION_TTL", &c.Auth.ImpersonationTTL)
+	setDuration("INVITATION_TTL", &c.Auth.InvitationTTL)
+	setDuration("ROLE_CACHE_TTL", &c.Auth.RoleCacheTTL)
+	setString("MFA_ISSUER", &c.Auth.MFAIssuer)
+	setBool("MFA_REQUIRE_ADMIN", &c.Auth.RequireAdminMFA)
+	setString("MFA_ENCRYPTION_KEY", &c.Auth.MFAEncryptionKey)
+
+	setInt("PASSWORD_MIN_LENGTH", &c.Auth.Password.MinLength)
+	setBool("PASSWORD_REQUIRE_UPPER", &c.Auth.Password.RequireUpper)
+	setBool("PASSWORD_REQUIRE_LOWER", &c.Auth.Password.RequireLower)
+	setBool("PASSWORD_REQUIRE_DIGIT", &c.Auth.Password.RequireDigit)
+	setBool("PASSWORD_REQUIRE_SYMBOL", &c.Auth.Password.RequireSymbol)
+	setInt("PASSWORD_HISTORY_SIZE", &c.Auth.Password.HistorySize)
+
+	// A single provider can be configured from the environment, more through the file
+	if name, ok := os.LookupEnv("OIDC_PROVIDER_NAME"); ok {
+		provider := OIDCProviderConfig{Name: name, Scopes: []string{"openid", "email", "profile"}}
+		setString("OIDC_ISSUER_URL", &provider.IssuerURL)
+		setString("OIDC_CLIENT_ID", &provider.ClientID)
+		setString("OIDC_CLIENT_SECRET", &provider.ClientSecret)
+		setString("OIDC_REDIRECT_URL", &provider.RedirectURL)
+		setList("OIDC_SCOPES", &provider.Scopes)
+		c.Auth.OIDCProviders = append(c.Auth.OIDCProviders, provider)
+	}
+
+	setInt64("UPLOAD_MAX_FILE_SIZE", &c.Upload.MaxFileSize)
+	setList("UPLOAD_ALLOWED_MIME_TYPES", &c.Upload.AllowedMimeTypes)
+
+	setString("ASSETS_DIR", &c.Assets.Dir)
+	setString("ASSETS_URL", &c.Assets.URL)
+	setString("CAR_ASSETS_DIR", &c.Assets.CarDir)
+
+	setString("MAIL_HOST", &c.Mail.Host)
+	setInt("MAIL_PORT", &c.Mail.Port)
+	setString("MAIL_USERNAME", &c.Mail.Username)
+	setString("MAIL_PASSWORD", &c.Mail.Password)
+	setString("MAIL_FROM", &c.Mail.From)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid environment:\n  - %s", strings.Join(errs, "\n  - "))
+	}
+
+	return nil
+}
+
+func isSupportedAlgorithm(alg string) bool {
+	return alg == "HS256" || alg == "RS256" || alg == "EdDSA"
+}
+
+func contains(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
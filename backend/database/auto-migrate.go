@@ -12,5 +12,20 @@ func DBMigrator(db *gorm.DB) error {
 		&models.CarTypes{},
 		&models.CarParent{},
 		&models.CarChild{},
+		&models.UserMFA{},
+		&models.MFAChallenge{},
+		&models.SigningKey{},
+		&models.UserIdentity{},
+		&models.Session{},
+		&models.PasswordHistory{},
+		&models.APIKey{},
+		&models.UserPermission{},
+		&models.AuditLog{},
+		&models.Setting{},
+		&models.UserInvitation{},
+		&models.BlacklistEntry{},
+		&models.CustomerProfile{},
+		&models.ErasureRequest{},
+		&models.SetupState{},
 	)
 }
\ No newline at end of file
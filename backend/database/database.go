@@ -2,32 +2,49 @@ package database
 
 import (
 	// "github.com/DestaAri1/models"
+	"github.com/DestaAri1/RentAuto/config"
 	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/utils"
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func Init(DBMigrator func(db *gorm.DB) error) *gorm.DB {
+func Init(cfg config.DatabaseConfig, DBMigrator func(db *gorm.DB) error) *gorm.DB {
+	logLevel := logger.Warn
+	if cfg.LogQueries {
+		logLevel = logger.Info
+	}
 
-	db, err := gorm.Open(mysql.Open("root:@/rentcar?charset=utf8mb4&parseTime=True&loc=Local"), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	db, err := gorm.Open(mysql.Open(cfg.DSN), &gorm.Config{
+		Logger: logger.Default.LogMode(logLevel),
 	})
 
 	if err != nil {
 		log.Fatal("Unable to connect DBL %e", err)
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Unable to access connection pool: %v", err)
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime.Duration)
+
 	log.Info("Success connect DB")
 
 	if err := DBMigrator(db); err != nil {
 		log.Fatal("Unable to migrate table %e", err)
 	}
 	seedData(db)
+	if cfg.SeedDemoUsers {
+		seedDemoUsers(db)
+	}
 
 	return db
 }
@@ -39,16 +56,19 @@ func seedData(db *gorm.DB) {
 			ID:         uuid.New(),
 			Name:       "super administrator",
 			Permission: []string{"all"},
+			IsSystem:   true,
 		},
 		{
 			ID:         uuid.New(),
 			Name:       "administrator",
 			Permission: []string{"all"},
+			IsSystem:   true,
 		},
 		{
 			ID:         uuid.New(),
 			Name:       "user",
 			Permission: nil,
+			IsSystem:   true,
 		},
 	}
 
@@ -59,12 +79,22 @@ func seedData(db *gorm.DB) {
 			if err := db.Create(&role).Error; err != nil {
 				log.Fatalf("Failed to create role: %v", err)
 			}
+		} else if !existingRole.IsSystem {
+			// Roles seeded before the flag existed
+			if err := db.Model(&existingRole).Update("is_system", true).Error; err != nil {
+				log.Fatalf("Failed to mark system role: %v", err)
+			}
 		}
 	}
 
 	log.Info("Seeding roles completed!")
+}
 
-	// 2. AMBIL ROLE ADMIN UNTUK DIPAKAI PADA USER
+// seedDemoUsers creates the demo accounts with the password 12345678, the
+// config only allows it in development. Real installations create their first
+// super administrator through the setup token instead.
+func seedDemoUsers(db *gorm.DB) {
+	// AMBIL ROLE ADMIN UNTUK DIPAKAI PADA USER
 	var adminRole models.Role
 	if err := db.First(&adminRole, "name = ?", "super administrator").Error; err != nil {
 		log.Fatalf("Failed to find role: %v", err)
@@ -75,7 +105,7 @@ func seedData(db *gorm.DB) {
 		log.Fatalf("Failed to find role: %v", err)
 	}
 
-	// 3. SEED USER
+	// SEED USER
 	users := []models.User{
 		{
 			ID:       uuid.New(),
@@ -105,17 +135,17 @@ func seedData(db *gorm.DB) {
 		}
 	}
 
-	log.Info("Seeding users completed!")
+	log.Info("Seeding demo users completed!")
 }
 
 
 // Fungsi untuk hash password
 func hashPassword(password string) string {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
 		log.Fatalf("Failed to hash password: %v", err)
 	}
-	return string(hashedPassword)
+	return hashedPassword
 }
 
 // func getRolePointer(role models.UserRole) *models.UserRole {
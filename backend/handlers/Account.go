@@ -0,0 +1,544 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/middlewares"
+	"github.com/DestaAri1/RentAuto/models"
+	validators "github.com/DestaAri1/RentAuto/validatiors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AccountHandler struct {
+	BaseHandler
+	Helper
+	service  models.AuthServices
+	sessions models.SessionServices
+	apiKeys  models.APIKeyServices
+	profiles models.CustomerProfileRepository
+	eligibility models.EligibilityServices
+	personalData models.PersonalDataRepository
+}
+
+func (h *AccountHandler) EnrollMFA(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	enrollment, err := h.service.EnrollMFA(context, userId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Scan the secret with your authenticator app", enrollment)
+}
+
+func (h *AccountHandler) ConfirmMFA(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	formData := &models.MFACodeForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Verification code is required")
+	}
+
+	codes, err := h.service.ConfirmMFA(context, userId, formData.Code)
+	if err != nil {
+		return h.mfaCodeError(ctx, err)
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Two-factor authentication enabled", fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+func (h *AccountHandler) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	formData := &models.MFACodeForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Verification code is required")
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(context, userId, formData.Code)
+	if err != nil {
+		return h.mfaCodeError(ctx, err)
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Recovery codes regenerated", fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+func (h *AccountHandler) DisableMFA(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	formData := &models.MFACodeForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Verification code is required")
+	}
+
+	if err := h.service.DisableMFA(context, userId, formData.Code); err != nil {
+		return h.mfaCodeError(ctx, err)
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+// mfaCodeError answers a rejected code, too many invalid codes lock the MFA
+// step for a while
+func (h *AccountHandler) mfaCodeError(ctx *fiber.Ctx, err error) error {
+	if errors.Is(err, models.ErrTooManyMFAAttempts) {
+		return h.handlerError(ctx, fiber.StatusTooManyRequests, err.Error())
+	}
+	return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+}
+
+func (h *AccountHandler) GetSessions(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	sessionId, err := h.GetSessionID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	sessions, err := h.sessions.ListSessions(context, userId, sessionId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", sessions)
+}
+
+func (h *AccountHandler) RevokeSession(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	sessionId, err := h.ParseUUID(ctx.Params("sessionId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid session ID format")
+	}
+
+	if err := h.sessions.RevokeSession(context, userId, sessionId); err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Session revoked", nil)
+}
+
+func (h *AccountHandler) RevokeOtherSessions(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	sessionId, err := h.GetSessionID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	revoked, err := h.sessions.RevokeOtherSessions(context, userId, sessionId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Other sessions revoked", fiber.Map{
+		"revoked": revoked,
+	})
+}
+
+func (h *AccountHandler) ChangePassword(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	sessionId, err := h.GetSessionID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	formData := &models.ChangePasswordForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Current and new password are required")
+	}
+
+	if err := h.service.ChangePassword(context, userId, sessionId, formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Password changed, other sessions have been signed out", nil)
+}
+
+func (h *AccountHandler) GetAPIKeys(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	keys, err := h.apiKeys.ListKeys(context, userId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", keys)
+}
+
+func (h *AccountHandler) CreateAPIKey(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	formData := &models.APIKeyForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Name and at least one scope are required, expiry must be between 1 and 365 days")
+	}
+
+	created, err := h.apiKeys.CreateKey(context, userId, userId, formData)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusCreated, "API key created, copy it now as it will not be shown again", created)
+}
+
+func (h *AccountHandler) RevokeAPIKey(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	keyId, err := h.ParseUUID(ctx.Params("keyId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid API key ID format")
+	}
+
+	if err := h.apiKeys.RevokeKey(context, userId, keyId); err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "API key revoked", nil)
+}
+
+// LinkIdentity starts a login with an identity provider that links it to the
+// signed in user, the only way to link accounts that are refused by email
+func (h *AccountHandler) LinkIdentity(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// Linking while impersonating would let the impersonator sign in as the user later
+	if _, ok := ctx.Locals("impersonatorId").(uuid.UUID); ok {
+		return h.handlerError(ctx, fiber.StatusForbidden, "Identities cannot be linked while impersonating")
+	}
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	authorization, err := h.service.OIDCAuthorize(context, ctx.Params("provider"), userId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Same cookie as a login, the callback tells both apart by the state
+	ctx.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    authorization.StateToken,
+		Path:     "/api/auth/oidc",
+		MaxAge:   600,
+		HTTPOnly: true,
+		Secure:   ctx.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Continue at the identity provider", fiber.Map{"url": authorization.URL})
+}
+
+// EndImpersonation revokes the impersonation session the request is made with
+func (h *AccountHandler) EndImpersonation(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, ok := ctx.Locals("impersonatorId").(uuid.UUID); !ok {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "You are not impersonating anyone")
+	}
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	sessionId, err := h.GetSessionID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	if err := h.sessions.RevokeSession(context, userId, sessionId); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Impersonation ended", nil)
+}
+
+func (h *AccountHandler) GetProfile(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	profile, err := h.profiles.GetProfile(context, userId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", profile)
+}
+
+func (h *AccountHandler) UpdateProfile(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	return saveProfileForm(ctx, &h.BaseHandler, context, h.profiles, userId)
+}
+
+// CheckEligibility tells the customer whether they may book for the rental
+// from start to end, both optional in RFC 3339
+func (h *AccountHandler) CheckEligibility(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	start, end, err := parseRentalPeriod(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	eligibility, err := h.eligibility.CheckEligibility(context, userId, start, end)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", eligibility)
+}
+
+// ExportPersonalData downloads everything stored about the user as a ZIP
+// archive holding data.json
+func (h *AccountHandler) ExportPersonalData(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	data, err := h.personalData.GetPersonalData(context, userId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	archive, err := personalDataArchive(data)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+
+	ctx.Attachment(fmt.Sprintf("rentauto-data-%s.zip", data.ExportedAt.Format("20060102-150405")))
+	ctx.Set(fiber.HeaderContentType, "application/zip")
+	return ctx.Status(fiber.StatusOK).Send(archive)
+}
+
+// GetErasureRequest returns the latest erasure request, data is null if the
+// user never asked
+func (h *AccountHandler) GetErasureRequest(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	request, err := h.personalData.GetErasureRequest(context, userId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", request)
+}
+
+// RequestErasure asks staff to erase the account, nothing changes until the
+// request is approved
+func (h *AccountHandler) RequestErasure(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	formData := &models.ErasureRequestForm{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(formData); err != nil {
+			return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+		}
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		userValidator := validators.NewUserValidator()
+		return h.handleValidationError(ctx, err, &userValidator)
+	}
+
+	request := &models.ErasureRequest{
+		UserID: userId,
+		Reason: strings.TrimSpace(formData.Reason),
+	}
+	if err := h.personalData.CreateErasureRequest(context, request); err != nil {
+		if errors.Is(err, models.ErrErasurePending) {
+			return h.handlerError(ctx, fiber.StatusConflict, err.Error())
+		}
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Erasure requested, we will let you know once it was reviewed", request)
+}
+
+func (h *AccountHandler) CancelErasureRequest(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	if err := h.personalData.CancelErasureRequest(context, userId); err != nil {
+		if errors.Is(err, models.ErrErasureNotFound) {
+			return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+		}
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Erasure request cancelled", nil)
+}
+
+func NewAccountHandler(router fiber.Router, service models.AuthServices, sessions models.SessionServices, apiKeys models.APIKeyServices, profiles models.CustomerProfileRepository, eligibility models.EligibilityServices, personalData models.PersonalDataRepository) {
+	handler := &AccountHandler{
+		service:  service,
+		sessions: sessions,
+		apiKeys:  apiKeys,
+		profiles: profiles,
+		eligibility: eligibility,
+		personalData: personalData,
+	}
+
+	// Support staff acting as the user must not change how the user signs in
+	blocked := middlewares.BlockImpersonation()
+
+	router.Post("/mfa/enroll", blocked, handler.EnrollMFA)
+	router.Post("/mfa/confirm", blocked, handler.ConfirmMFA)
+	router.Post("/mfa/recovery-codes", blocked, handler.RegenerateRecoveryCodes)
+	router.Delete("/mfa", blocked, handler.DisableMFA)
+	router.Post("/password", blocked, handler.ChangePassword)
+	router.Get("/sessions", handler.GetSessions)
+	router.Delete("/sessions", blocked, handler.RevokeOtherSessions)
+	router.Delete("/sessions/:sessionId", blocked, handler.RevokeSession)
+	router.Get("/api-keys", handler.GetAPIKeys)
+	router.Post("/api-keys", blocked, handler.CreateAPIKey)
+	router.Delete("/api-keys/:keyId", blocked, handler.RevokeAPIKey)
+	router.Delete("/impersonation", handler.EndImpersonation)
+	router.Get("/identities/:provider/link", blocked, handler.LinkIdentity)
+	router.Get("/profile", handler.GetProfile)
+	router.Put("/profile", handler.UpdateProfile)
+	router.Get("/eligibility", handler.CheckEligibility)
+	router.Get("/export", blocked, handler.ExportPersonalData)
+	router.Get("/erasure", handler.GetErasureRequest)
+	router.Post("/erasure", blocked, handler.RequestErasure)
+	router.Delete("/erasure", blocked, handler.CancelErasureRequest)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/DestaAri1/RentAuto/middlewares"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/policy"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditLogHandler struct {
+	BaseHandler
+	Helper
+	repository models.AuditLogRepository
+}
+
+// GetAuditLogs lists audit entries, newest first. Filters: actor,
+// impersonator, entity, entity_id, action and a from/to time range in RFC 3339.
+func (h *AuditLogHandler) GetAuditLogs(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := &models.AuditLogFilter{
+		Entity: ctx.Query("entity"),
+		Action: ctx.Query("action"),
+	}
+
+	if actor := ctx.Query("actor"); actor != "" {
+		actorId, err := h.ParseUUID(actor)
+		if err != nil {
+			return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid actor ID format")
+		}
+		filter.ActorID = actorId
+	}
+
+	if impersonator := ctx.Query("impersonator"); impersonator != "" {
+		impersonatorId, err := h.ParseUUID(impersonator)
+		if err != nil {
+			return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid impersonator ID format")
+		}
+		filter.ImpersonatorID = impersonatorId
+	}
+
+	if entity := ctx.Query("entity_id"); entity != "" {
+		entityId, err := h.ParseUUID(entity)
+		if err != nil {
+			return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid entity ID format")
+		}
+		filter.EntityID = entityId
+	}
+
+	from, err := parseTimeQuery(ctx, "from")
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid from time, use RFC 3339")
+	}
+	filter.From = from
+
+	to, err := parseTimeQuery(ctx, "to")
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid to time, use RFC 3339")
+	}
+	filter.To = to
+
+	logs, err := h.repository.GetAuditLogs(context, filter, h.ParsePageQuery(ctx))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", logs)
+}
+
+// parseTimeQuery reads an optional RFC 3339 query parameter
+func parseTimeQuery(ctx *fiber.Ctx, param string) (*time.Time, error) {
+	value := ctx.Query(param)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// NewAuditLogHandler exposes the audit log to roles that pass
+// AdminPolicy.CanViewSystemLogs, the system.logs permission
+func NewAuditLogHandler(router fiber.Router, repository models.AuditLogRepository, authorizer *middlewares.Authorizer) {
+	handler := &AuditLogHandler{
+		repository: repository,
+	}
+
+	authorizer.Get(router, "/", policy.PermSystemLogs, handler.GetAuditLogs)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/DestaAri1/RentAuto/middlewares"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/policy"
+	validators "github.com/DestaAri1/RentAuto/validatiors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type BlacklistHandler struct {
+	BaseHandler
+	Helper
+	repository models.BlacklistRepository
+}
+
+func (h *BlacklistHandler) GetBlacklist(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	entries, err := h.repository.GetBlacklist(context, h.ParsePageQuery(ctx))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", entries)
+}
+
+// CreateBlacklistEntry blacklists a person who may not have an account, at
+// least one of email, phone and id_number is required
+func (h *BlacklistHandler) CreateBlacklistEntry(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	adminId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	formData := &models.BlacklistForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		userValidator := validators.NewUserValidator()
+		return h.handleValidationError(ctx, err, &userValidator)
+	}
+
+	entry := &models.BlacklistEntry{
+		Email:     models.NormalizeEmail(formData.Email),
+		Phone:     models.NormalizePhone(formData.Phone),
+		IDNumber:  models.NormalizeIDNumber(formData.IDNumber),
+		Reason:    formData.Reason,
+		ExpiresAt: formData.ExpiresAt,
+		CreatedBy: adminId,
+	}
+	if entry.Email == "" && entry.Phone == "" && entry.IDNumber == "" {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Email, phone or ID number is required")
+	}
+
+	if entry.ExpiresAt != nil && !entry.ExpiresAt.After(time.Now()) {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Expiry must be in the future")
+	}
+
+	if err := h.repository.CreateBlacklistEntry(context, entry); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Blacklist entry created", entry)
+}
+
+func (h *BlacklistHandler) DeleteBlacklistEntry(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	entryId, err := h.ParseUUID(ctx.Params("entryId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid blacklist entry ID format")
+	}
+
+	deleted, err := h.repository.DeleteBlacklistEntry(context, entryId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+	if deleted == 0 {
+		return h.handlerError(ctx, fiber.StatusNotFound, "Blacklist entry not found")
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Blacklist entry removed", nil)
+}
+
+func NewBlacklistHandler(router fiber.Router, repository models.BlacklistRepository, authorizer *middlewares.Authorizer) {
+	handler := &BlacklistHandler{
+		repository: repository,
+	}
+
+	authorizer.Get(router, "/", policy.PermUserManage, handler.GetBlacklist)
+	authorizer.Post(router, "/", policy.PermUserManage, handler.CreateBlacklistEntry)
+	authorizer.Delete(router, "/:entryId", policy.PermUserManage, handler.DeleteBlacklistEntry)
+}
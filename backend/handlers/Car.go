@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/DestaAri1/RentAuto/middlewares"
 	"github.com/DestaAri1/RentAuto/models"
 	"github.com/DestaAri1/RentAuto/policy"
 	validators "github.com/DestaAri1/RentAuto/validatiors"
@@ -15,12 +16,13 @@ import (
 
 type CarHandler struct {
 	BaseHandler
+	Helper
 	repository models.CarRepository
 	carPolicy  *policy.CarPolicy
 }
 
 func (h *CarHandler) GetCars(ctx *fiber.Ctx) error {
-	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second))
+	context, cancel := context.WithTimeout(ctx.UserContext(), time.Duration(5*time.Second))
     defer cancel()
 
 	res, err := h.repository.GetCars(context)
@@ -32,25 +34,14 @@ func (h *CarHandler) GetCars(ctx *fiber.Ctx) error {
 }
 
 func (h *CarHandler) CreateCar(ctx *fiber.Ctx) error {
-	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second))
+	context, cancel := context.WithTimeout(ctx.UserContext(), time.Duration(5*time.Second))
     defer cancel()
 
-	// Get role ID from context (assuming it's set by middleware)
-	roleId, ok := ctx.Locals("roleId").(uuid.UUID)
-	if !ok {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, "Role ID not found in context")
-	}
-
 	userId, ok := ctx.Locals("userId").(uuid.UUID)
 	if !ok {
 		return h.handlerError(ctx, fiber.StatusUnauthorized, "User ID not found in context")
 	}
 
-	if err := h.carPolicy.CanCreateCar(context, roleId); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to create cars")
-	}
-
-
 	// Parse form data first
 	formData := &models.FormCarParent{}
 	if err := ctx.BodyParser(formData); err != nil {
@@ -75,23 +66,14 @@ func (h *CarHandler) CreateCar(ctx *fiber.Ctx) error {
 }
 
 func (h *CarHandler) UpdateCar(ctx *fiber.Ctx) error {
-	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second)) 
+	context, cancel := context.WithTimeout(ctx.UserContext(), time.Duration(5*time.Second)) 
 	defer cancel()
 
-	// Get role ID from context (assuming it's set by middleware)
-	roleId, ok := ctx.Locals("roleId").(uuid.UUID)
-	if !ok {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, "Role ID not found in context")
-	}
-
-	userId, ok := ctx.Locals("userId").(uuid.UUID)
-	if !ok {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, "User ID not found in context")
-	}
-
-	if err := h.carPolicy.CanUpdateCar(context, roleId); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to update cars")
+	subject, err := h.GetSubject(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
 	}
+	userId := subject.UserID
 
 	carIdParam := ctx.Params("carId")
 	carId, err := uuid.Parse(carIdParam)
@@ -99,6 +81,15 @@ func (h *CarHandler) UpdateCar(ctx *fiber.Ctx) error {
 		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid car ID format")
 	}
 
+	car, err := h.repository.GetCar(context, carId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, "Car not found")
+	}
+
+	if err := h.carPolicy.CanUpdateCar(context, subject, car); err != nil {
+		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to update this car")
+	}
+
 	// Parse form data first
 	formData := &models.FormCarParent{}
 	if err := ctx.BodyParser(formData); err != nil {
@@ -142,17 +133,12 @@ func (h *CarHandler) UpdateCar(ctx *fiber.Ctx) error {
 }
 
 func (h *CarHandler) DeleteCar(ctx *fiber.Ctx) error {
-	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second)) 
+	context, cancel := context.WithTimeout(ctx.UserContext(), time.Duration(5*time.Second)) 
 	defer cancel()
 
-	// Get role ID from context (assuming it's set by middleware)
-	roleId, ok := ctx.Locals("roleId").(uuid.UUID)
-	if !ok {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, "Role ID not found in context")
-	}
-
-	if err := h.carPolicy.CanDeleteCar(context, roleId); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to delete cars")
+	subject, err := h.GetSubject(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
 	}
 
 	carIdParam := ctx.Params("carId")
@@ -161,6 +147,15 @@ func (h *CarHandler) DeleteCar(ctx *fiber.Ctx) error {
 		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid car ID format")
 	}
 
+	target, err := h.repository.GetCar(context, carId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, "Car not found")
+	}
+
+	if err := h.carPolicy.CanDeleteCar(context, subject, target); err != nil {
+		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to delete this car")
+	}
+
 	car := h.repository.DeleteCar(context, carId)
 	if car != nil {
 		return h.handlerError(ctx, fiber.StatusBadGateway, car.Error())
@@ -169,19 +164,14 @@ func (h *CarHandler) DeleteCar(ctx *fiber.Ctx) error {
 	return h.handlerSuccess(ctx, fiber.StatusOK, "Car delete successfully!", nil)
 }
 
-func NewCarHandler(router fiber.Router, repository models.CarRepository, roleRepo models.RoleRepository) {
-	basePolicy := policy.NewPolicy(roleRepo)
-	carPolicy := &policy.CarPolicy{
-		Policy: basePolicy,
-	}
-	
+func NewCarHandler(router fiber.Router, repository models.CarRepository, authorizer *middlewares.Authorizer, carPolicy *policy.CarPolicy) {
 	handler := &CarHandler{
 		repository: repository,
 		carPolicy:  carPolicy,
 	}
 
-	router.Get("/", handler.GetCars)
-	router.Post("/", handler.CreateCar)
-	router.Patch("/:carId", handler.UpdateCar)
-	router.Delete("/:carId", handler.DeleteCar)
+	authorizer.Get(router, "/", policy.PermCarView, handler.GetCars)
+	authorizer.Post(router, "/", policy.PermCarCreate, handler.CreateCar)
+	authorizer.Patch(router, "/:carId", policy.PermCarUpdate, handler.UpdateCar)
+	authorizer.Delete(router, "/:carId", policy.PermCarDelete, handler.DeleteCar)
 }
\ No newline at end of file
@@ -3,9 +3,9 @@ package handlers
 import (
 	"context"
 	"path/filepath"
-	"strings"
 	"time"
 
+	"github.com/DestaAri1/RentAuto/middlewares"
 	"github.com/DestaAri1/RentAuto/models"
 	"github.com/DestaAri1/RentAuto/policy"
 	"github.com/DestaAri1/RentAuto/utils"
@@ -20,29 +20,37 @@ type CarChildHandler struct {
 	BaseHandler
 	Helper
 	repository models.CarChildRepository
-	carChildPolicy    *policy.CarPolicy
+	carPolicy  *policy.CarPolicy
 	validatorManager *validators.ValidatorManager
 }
 
-func (h *CarChildHandler) CheckPermission(ctx context.Context, roleId uuid.UUID, action string) error {
-	var err error
+// authorizeUnit checks the subject may update the car a unit belongs to,
+// units have no owner of their own
+func (h *CarChildHandler) authorizeUnit(ctx *fiber.Ctx, context context.Context, carChildId uuid.UUID, action string) error {
+	subject, err := h.GetSubject(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
 
-	switch action {
-	case "getOne" :
-		err = h.carChildPolicy.CanEditCar(ctx, roleId)
-	case "create" :
-		err = h.carChildPolicy.CanCreateCar(ctx, roleId)
-	case "update" :
-		err = h.carChildPolicy.CanUpdateCar(ctx, roleId)
-	case "delete" :
-		err = h.carChildPolicy.CanDeleteCar(ctx, roleId)
+	carParent, err := h.repository.GetCarChildParent(context, carChildId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, "Car child not found")
+	}
+
+	if action == "delete" {
+		err = h.carPolicy.CanDeleteCar(context, subject, carParent)
+	} else {
+		err = h.carPolicy.CanUpdateCar(context, subject, carParent)
+	}
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to "+action+" this car")
 	}
 
-	return err
+	return nil
 }
 
 func (h *CarChildHandler) GetCarChild(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 *time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 *time.Second)
 	defer cancel()
 
 	slugStr := ctx.Params("carSlug")
@@ -57,18 +65,9 @@ func (h *CarChildHandler) GetCarChild(ctx *fiber.Ctx) error {
 }
 
 func (h *CarChildHandler) GetOneCarChild(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 *time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 *time.Second)
 	defer cancel()
 
-	roleId, err := h.GetRoleID(ctx)
-	if err != nil {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
-	}
-
-	if err := h.CheckPermission(context, roleId, "getOne"); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to view cars")
-	}
-
 	carChildId := ctx.Params("carChildSlug")
 	
 	result, err := h.repository.GetOneCarChild(context, carChildId)
@@ -81,18 +80,9 @@ func (h *CarChildHandler) GetOneCarChild(ctx *fiber.Ctx) error {
 }
 
 func (h *CarChildHandler) CreateCarChild(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 * time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
 	defer cancel()
 
-	roleId, err := h.GetRoleID(ctx)
-	if err != nil {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
-	}
-
-	if err := h.CheckPermission(context, roleId, "create"); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to create car")
-	}
-
 	userId, err := h.GetUserID(ctx)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
@@ -116,7 +106,7 @@ func (h *CarChildHandler) CreateCarChild(ctx *fiber.Ctx) error {
 	formData.CarParentId = parentId.(uuid.UUID)
 
 	// Handle file upload - check if file exists first
-	file, err := h.ParseFormValue(ctx, "image", "file", "assets/car")
+	file, err := h.ParseFormValue(ctx, "image", "file", utils.CarAssetsDir)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
 	}
@@ -127,7 +117,7 @@ func (h *CarChildHandler) CreateCarChild(ctx *fiber.Ctx) error {
 	if err := validator.New().Struct(formData); err != nil {
 		// Clean up uploaded file if validation fails
 		if formData.Image != "default.png" {
-			utils.DeleteFile(filepath.Join("assets/car", formData.Image))
+			utils.DeleteFile(filepath.Join(utils.CarAssetsDir, formData.Image))
 		}
 		validator := validators.NewCarChildValidator()
 		return h.handleValidationError(ctx, err, &validator)
@@ -138,7 +128,7 @@ func (h *CarChildHandler) CreateCarChild(ctx *fiber.Ctx) error {
 	if err != nil {
 		// Clean up uploaded file if creation fails
 		if formData.Image != "default.png" {
-			utils.DeleteFile(filepath.Join("assets/car", formData.Image))
+			utils.DeleteFile(filepath.Join(utils.CarAssetsDir, formData.Image))
 		}
 		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
 	}
@@ -147,18 +137,9 @@ func (h *CarChildHandler) CreateCarChild(ctx *fiber.Ctx) error {
 }
 
 func (h *CarChildHandler) UpdateStatusCarChild(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 * time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
 	defer cancel()
 
-	roleId, err := h.GetRoleID(ctx)
-	if err != nil {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
-	}
-
-	if err := h.CheckPermission(context, roleId, "update"); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to update car")
-	}
-
 	userId, err := h.GetUserID(ctx)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
@@ -169,6 +150,10 @@ func (h *CarChildHandler) UpdateStatusCarChild(ctx *fiber.Ctx) error {
 		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
 	}
 
+	if err := h.authorizeUnit(ctx, context, carChildId, "update"); err != nil {
+		return err
+	}
+
 	formData := &models.StatusForm{}
 	if err := ctx.BodyParser(formData); err != nil {
 		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
@@ -201,18 +186,9 @@ func (h *CarChildHandler) UpdateStatusCarChild(ctx *fiber.Ctx) error {
 }
 
 func (h *CarChildHandler) UpdateCarChild(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 * time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
 	defer cancel()
 
-	roleId, err := h.GetRoleID(ctx)
-	if err != nil {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
-	}
-
-	if err := h.CheckPermission(context, roleId, "update"); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to update car")
-	}
-
 	userId, err := h.GetUserID(ctx)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
@@ -223,6 +199,10 @@ func (h *CarChildHandler) UpdateCarChild(ctx *fiber.Ctx) error {
 		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
 	}
 
+	if err := h.authorizeUnit(ctx, context, carChildId, "update"); err != nil {
+		return err
+	}
+
 	formData := &models.FormUpdateCarChild{}
 	if err := ctx.BodyParser(formData); err != nil {
 		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
@@ -235,21 +215,36 @@ func (h *CarChildHandler) UpdateCarChild(ctx *fiber.Ctx) error {
 
 	formData.CarParentId = parentId.(uuid.UUID)
 
+	// Moving the unit to another car needs the right to update that car too
+	subject, err := h.GetSubject(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	newParent, err := h.repository.GetCarParent(context, formData.CarParentId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Car parent not found")
+	}
+
+	if err := h.carPolicy.CanUpdateCar(context, subject, newParent); err != nil {
+		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to move units to this car")
+	}
+
 	// Handle file upload if provided
 	file, err := ctx.FormFile("image")
 	if err == nil {
 		// File was provided, validate and process it
 		contentType := file.Header.Get("Content-Type")
-		if !strings.Contains(utils.AllowedMimeTypes, contentType) {
+		if !utils.IsAllowedMimeType(contentType) {
 			return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid file type. Allowed types: jpg, jpeg, png")
 		}
 
-		if file.Size > utils.MaxFileSize {
-			return h.handlerError(ctx, fiber.StatusBadRequest, "File size exceeds maximum limit of 5MB")
+		if file.Size > utils.MaxFileSize() {
+			return h.handlerError(ctx, fiber.StatusBadRequest, "File size exceeds maximum limit of "+utils.MaxFileSizeLabel())
 		}
 
 		// Save new uploaded file
-		filename, err := utils.SaveUploadedFile(file, "assets/car")
+		filename, err := utils.SaveUploadedFile(file, utils.CarAssetsDir)
 		if err != nil {
 			return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
 		}
@@ -261,7 +256,7 @@ func (h *CarChildHandler) UpdateCarChild(ctx *fiber.Ctx) error {
 	if err := validator.New().Struct(formData); err != nil {
 		// Clean up uploaded file if validation fails
 		if formData.Image != "" {
-			utils.DeleteFile(filepath.Join("assets/car", formData.Image))
+			utils.DeleteFile(filepath.Join(utils.CarAssetsDir, formData.Image))
 		}
 		validator := validators.NewCarChildValidator()
 		return h.handleValidationError(ctx, err, &validator)
@@ -303,7 +298,7 @@ func (h *CarChildHandler) UpdateCarChild(ctx *fiber.Ctx) error {
 	if err != nil {
 		// Clean up uploaded file if update fails
 		if filename, ok := updatedData["image_url"].(string); ok {
-			utils.DeleteFile(filepath.Join("assets/car", filename))
+			utils.DeleteFile(filepath.Join(utils.CarAssetsDir, filename))
 		}
 		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
 	}
@@ -312,46 +307,36 @@ func (h *CarChildHandler) UpdateCarChild(ctx *fiber.Ctx) error {
 }
 
 func (h *CarChildHandler) DeleteCar(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 *time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 *time.Second)
 	defer cancel()
 
-	roleId, err := h.GetRoleID(ctx)
-	if err != nil {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
-	}
-
-	if err := h.CheckPermission(context, roleId, "delete"); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to update car")
-	}
-
 	carChildId, err := h.ParseUUID(ctx.Params("carChildId"))
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
 	}
 
+	if err := h.authorizeUnit(ctx, context, carChildId, "delete"); err != nil {
+		return err
+	}
+
 	res := h.repository.DeleteCarChild(context, carChildId)
 	if res != nil {
-		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+		return h.handlerError(ctx, fiber.StatusBadRequest, res.Error())
 	}
 
 	return h.handlerSuccess(ctx, fiber.StatusOK, "Success delete", "")
 }
 
-func NewCarChildHandler(router fiber.Router, repository models.CarChildRepository, roleRepo models.RoleRepository) {
-	basePolicy := policy.NewPolicy(roleRepo)
-	carPolicy := &policy.CarPolicy{
-		Policy: basePolicy,
-	}
-	
+func NewCarChildHandler(router fiber.Router, repository models.CarChildRepository, authorizer *middlewares.Authorizer, carPolicy *policy.CarPolicy) {
 	handler := &CarChildHandler{
 		repository: repository,
-		carChildPolicy:  carPolicy,
+		carPolicy:  carPolicy,
 	}
 
-	router.Get("/:carSlug", handler.GetCarChild)
-	router.Get("/view/:carChildSlug", handler.GetOneCarChild)
-	router.Post("/", handler.CreateCarChild)
-	router.Patch("/update-status/:carChildId", handler.UpdateStatusCarChild)
-	router.Patch("/:carChildId", handler.UpdateCarChild)
-	router.Delete("/:carChildId", handler.DeleteCar)
+	authorizer.Get(router, "/:carSlug", policy.PermCarView, handler.GetCarChild)
+	authorizer.Get(router, "/view/:carChildSlug", policy.PermCarView, handler.GetOneCarChild)
+	authorizer.Post(router, "/", policy.PermCarCreate, handler.CreateCarChild)
+	authorizer.Patch(router, "/update-status/:carChildId", policy.PermCarUpdate, handler.UpdateStatusCarChild)
+	authorizer.Patch(router, "/:carChildId", policy.PermCarUpdate, handler.UpdateCarChild)
+	authorizer.Delete(router, "/:carChildId", policy.PermCarDelete, handler.DeleteCar)
 }
\ No newline at end of file
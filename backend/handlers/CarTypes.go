@@ -2,14 +2,13 @@
 package handlers
 
 import (
-	"context"
 	"time"
 
+	"github.com/DestaAri1/RentAuto/middlewares"
 	"github.com/DestaAri1/RentAuto/models"
 	"github.com/DestaAri1/RentAuto/policy"
 	validators "github.com/DestaAri1/RentAuto/validatiors"
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 )
 
 type CarTypesRepository struct {
@@ -20,26 +19,8 @@ type CarTypesRepository struct {
 	validatorManager *validators.ValidatorManager
 }
 
-// CheckPermission verifies user has permission to perform an action
-func (h *CarTypesRepository) CheckPermission(ctx context.Context, roleId uuid.UUID, action string) error {
-	var err error
-
-	switch action {
-	case "create":
-		err = h.carTypePolicy.CanCreateCarTypes(ctx, roleId)
-	case "update":
-		err = h.carTypePolicy.CanUpdateCarTypes(ctx, roleId)
-	case "delete":
-		err = h.carTypePolicy.CanDeleteCarTypes(ctx, roleId)
-	default:
-		err = fiber.NewError(fiber.StatusForbidden, "Unknown permission action")
-	}
-
-	return err
-}
-
 func (h *CarTypesRepository) GetCarType(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 * time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
 	defer cancel()
 
 	res, err := h.repository.GetCarType(context)
@@ -52,19 +33,9 @@ func (h *CarTypesRepository) GetCarType(ctx *fiber.Ctx) error {
 }
 
 func (h *CarTypesRepository) CreateCarType(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 * time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
 	defer cancel()
 
-	// Get role ID from context (assuming it's set by middleware)
-	roleId, err := h.GetRoleID(ctx)
-	if err != nil {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
-	}
-
-	if err := h.CheckPermission(context, roleId, "create"); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to create car types")
-	}
-
 	userId, err := h.GetUserID(ctx)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
@@ -91,27 +62,27 @@ func (h *CarTypesRepository) CreateCarType(ctx *fiber.Ctx) error {
 }
 
 func (h *CarTypesRepository) UpdateCarType(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 * time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
 	defer cancel()
 
-	// Get role ID from context (assuming it's set by middleware)
-	roleId, err := h.GetRoleID(ctx)
+	subject, err := h.GetSubject(ctx)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
 	}
+	userId := subject.UserID
 
-	if err := h.CheckPermission(context, roleId, "update"); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to update car types")
+	carTypeId, err := h.ParseUUID(ctx.Params("carTypeId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid car type ID format")
 	}
 
-	userId, err := h.GetUserID(ctx)
+	carType, err := h.repository.GetCarTypeByID(context, carTypeId)
 	if err != nil {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+		return h.handlerError(ctx, fiber.StatusNotFound, "Car type not found")
 	}
 
-	carTypeId, err := h.ParseUUID(ctx.Params("carTypeId"))
-	if err != nil {
-		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid car type ID format")
+	if err := h.carTypePolicy.CanUpdateCarTypes(context, subject, carType); err != nil {
+		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to update this car type")
 	}
 
 	updatedData := make(map[string]interface{})
@@ -142,24 +113,28 @@ func (h *CarTypesRepository) UpdateCarType(ctx *fiber.Ctx) error {
 }
 
 func (h *CarTypesRepository) DeleteCarType(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 * time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
 	defer cancel()
 
-	// Get role ID from context (assuming it's set by middleware)
-	roleId, err := h.GetRoleID(ctx)
+	subject, err := h.GetSubject(ctx)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
 	}
 
-	if err := h.CheckPermission(context, roleId, "delete"); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to delete car types")
-	}
-
 	carTypeId, err := h.ParseUUID(ctx.Params("carTypeId"))
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
+	carType, err := h.repository.GetCarTypeByID(context, carTypeId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, "Car type not found")
+	}
+
+	if err := h.carTypePolicy.CanDeleteCarTypes(context, subject, carType); err != nil {
+		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to delete this car type")
+	}
+
 	err = h.repository.DeleteCarType(context, carTypeId)
 
 	if err != nil {
@@ -169,20 +144,15 @@ func (h *CarTypesRepository) DeleteCarType(ctx *fiber.Ctx) error {
 	return h.handlerSuccess(ctx, fiber.StatusOK, "Data successfully deleted", nil)
 }
 
-func NewCarTypesHandler(router fiber.Router, repository models.CarTypesRepository, roleRepo models.RoleRepository, validatorManager *validators.ValidatorManager) {
-	basePolicy := policy.NewPolicy(roleRepo)
-	carTypesPolicy := &policy.CarTypesPolicy{
-		Policy: basePolicy,
-	}
-
+func NewCarTypesHandler(router fiber.Router, repository models.CarTypesRepository, authorizer *middlewares.Authorizer, carTypePolicy *policy.CarTypesPolicy, validatorManager *validators.ValidatorManager) {
 	handler := &CarTypesRepository{
 		repository:       repository,
-		carTypePolicy:    carTypesPolicy,
+		carTypePolicy:    carTypePolicy,
 		validatorManager: validatorManager,
 	}
 
-	router.Get("/", handler.GetCarType)
-	router.Post("/", handler.CreateCarType)
-	router.Patch("/:carTypeId", handler.UpdateCarType)
-	router.Delete("/:carTypeId", handler.DeleteCarType)
+	authorizer.Get(router, "/", policy.PermCarTypeView, handler.GetCarType)
+	authorizer.Post(router, "/", policy.PermCarTypeCreate, handler.CreateCarType)
+	authorizer.Patch(router, "/:carTypeId", policy.PermCarTypeUpdate, handler.UpdateCarType)
+	authorizer.Delete(router, "/:carTypeId", policy.PermCarTypeDelete, handler.DeleteCarType)
 }
\ No newline at end of file
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	validators "github.com/DestaAri1/RentAuto/validatiors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// eligibilityWindow is the rental checked when the query names no end
+const eligibilityWindow = 24 * time.Hour
+
+// saveProfileForm validates the submitted profile of userId and saves it, it
+// is shared by the account and the admin endpoints
+func saveProfileForm(ctx *fiber.Ctx, h *BaseHandler, context context.Context, profiles models.CustomerProfileRepository, userId uuid.UUID) error {
+	formData := &models.CustomerProfileForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		userValidator := validators.NewUserValidator()
+		return h.handleValidationError(ctx, err, &userValidator)
+	}
+
+	profile, problems := formData.Profile(userId, time.Now())
+	if len(problems) > 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "fail",
+			"message": "Validation error",
+			"errors":  problems,
+		})
+	}
+
+	if err := profiles.SaveProfile(context, profile); err != nil {
+		if errors.Is(err, models.ErrProfileBlacklisted) {
+			return h.handlerError(ctx, fiber.StatusForbidden, err.Error())
+		}
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Profile saved", profile)
+}
+
+// parseRentalPeriod reads start and end in RFC 3339, start defaults to now
+// and end to a day after start
+func parseRentalPeriod(ctx *fiber.Ctx) (time.Time, time.Time, error) {
+	start := time.Now()
+	from, err := parseTimeQuery(ctx, "start")
+	if err != nil {
+		return start, start, errors.New("Invalid start time, use RFC 3339")
+	}
+	if from != nil {
+		start = *from
+	}
+
+	end := start.Add(eligibilityWindow)
+	until, err := parseTimeQuery(ctx, "end")
+	if err != nil {
+		return start, end, errors.New("Invalid end time, use RFC 3339")
+	}
+	if until != nil {
+		end = *until
+	}
+
+	if !end.After(start) {
+		return start, end, errors.New("The end must be after the start")
+	}
+
+	return start, end, nil
+}
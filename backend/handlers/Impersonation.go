@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/middlewares"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/policy"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type ImpersonationHandler struct {
+	BaseHandler
+	Helper
+	service models.AuthServices
+}
+
+// StartImpersonation returns a token that acts as the user until it expires or
+// the impersonator ends it through DELETE /api/account/impersonation
+func (h *ImpersonationHandler) StartImpersonation(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	impersonatorId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	result, err := h.service.Impersonate(context, impersonatorId, userId, clientInfo(ctx))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return h.handlerError(ctx, fiber.StatusNotFound, "User not found")
+	case errors.Is(err, models.ErrCannotImpersonate):
+		return h.handlerError(ctx, fiber.StatusForbidden, err.Error())
+	case err != nil:
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Impersonation started", result)
+}
+
+func NewImpersonationHandler(router fiber.Router, service models.AuthServices, authorizer *middlewares.Authorizer) {
+	handler := &ImpersonationHandler{
+		service: service,
+	}
+
+	authorizer.Post(router, "/:userId", policy.PermUserImpersonate, middlewares.BlockImpersonation(), handler.StartImpersonation)
+}
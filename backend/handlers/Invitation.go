@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/middlewares"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/policy"
+	validators "github.com/DestaAri1/RentAuto/validatiors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type InvitationHandler struct {
+	BaseHandler
+	Helper
+	service models.InvitationServices
+}
+
+// AcceptInvitation sets the password of an invited user, afterwards they sign
+// in through /api/auth/login like everyone else
+func (h *InvitationHandler) AcceptInvitation(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	formData := &models.AcceptInvitationForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		userValidator := validators.NewUserValidator()
+		return h.handleValidationError(ctx, err, &userValidator)
+	}
+
+	err := h.service.Accept(context, formData)
+	switch {
+	case errors.Is(err, models.ErrInvalidInvitation):
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	case err != nil:
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Password set, you can now sign in", nil)
+}
+
+// GetPendingInvitations lists invitations that were neither accepted nor
+// revoked, expired ones included
+func (h *InvitationHandler) GetPendingInvitations(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	invitations, err := h.service.GetPendingInvitations(context, h.ParsePageQuery(ctx))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", invitations)
+}
+
+// ResendInvitation emails a new link with a new expiry, the previous link
+// stops working
+func (h *InvitationHandler) ResendInvitation(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	invitationId, err := h.ParseUUID(ctx.Params("invitationId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid invitation ID format")
+	}
+
+	adminId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	err = h.service.Resend(context, invitationId, adminId)
+	switch {
+	case errors.Is(err, models.ErrInvitationNotPending):
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	case err != nil:
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Invitation sent again", nil)
+}
+
+// RevokeInvitation makes the link unusable, the user is kept without a
+// password
+func (h *InvitationHandler) RevokeInvitation(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	invitationId, err := h.ParseUUID(ctx.Params("invitationId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid invitation ID format")
+	}
+
+	err = h.service.Revoke(context, invitationId)
+	switch {
+	case errors.Is(err, models.ErrInvitationNotPending):
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	case err != nil:
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Invitation revoked", nil)
+}
+
+func NewInvitationHandler(router fiber.Router, service models.InvitationServices) {
+	handler := &InvitationHandler{
+		service: service,
+	}
+
+	router.Post("/accept", handler.AcceptInvitation)
+}
+
+func NewInvitationAdminHandler(router fiber.Router, service models.InvitationServices, authorizer *middlewares.Authorizer) {
+	handler := &InvitationHandler{
+		service: service,
+	}
+
+	authorizer.Get(router, "/", policy.PermUserManage, handler.GetPendingInvitations)
+	authorizer.Post(router, "/:invitationId/resend", policy.PermUserManage, handler.ResendInvitation)
+	authorizer.Delete(router, "/:invitationId", policy.PermUserManage, handler.RevokeInvitation)
+}
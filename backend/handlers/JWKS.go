@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type JWKSHandler struct {
+	keyManager models.KeyManager
+}
+
+// GetJWKS publishes the public signing keys so other services can verify our tokens
+func (h *JWKSHandler) GetJWKS(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.Status(fiber.StatusOK).JSON(h.keyManager.JWKS())
+}
+
+func NewJWKSHandler(router fiber.Router, keyManager models.KeyManager) {
+	handler := &JWKSHandler{
+		keyManager: keyManager,
+	}
+
+	router.Get("/jwks.json", handler.GetJWKS)
+}
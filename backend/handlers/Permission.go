@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"github.com/DestaAri1/RentAuto/middlewares"
+	"github.com/DestaAri1/RentAuto/policy"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PermissionHandler struct {
+	BaseHandler
+	Helper
+}
+
+// GetPermissions returns the permission registry grouped by resource for the role editor
+func (h *PermissionHandler) GetPermissions(ctx *fiber.Ctx) error {
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", policy.Registry)
+}
+
+func NewPermissionHandler(router fiber.Router, authorizer *middlewares.Authorizer) {
+	handler := &PermissionHandler{}
+
+	authorizer.Get(router, "/", policy.PermRoleManage, handler.GetPermissions)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/middlewares"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/policy"
+	validators "github.com/DestaAri1/RentAuto/validatiors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// personalDataReadme explains the archive to the customer, reviews and
+// documents are named because customers ask for them
+const personalDataReadme = `This archive holds the personal data RentAuto stores about your account.
+
+data.json contains:
+- user: your account, its role and status
+- profile: contact, date of birth and driving licence details
+- orders: your rentals, including their payment status
+- sessions, identities, api_keys: how and where you signed in
+- permissions: permissions granted to or withdrawn from your account
+- erasure_requests: your requests to have the account erased
+- audit_logs: the recorded changes to your account and profile
+
+RentAuto does not store reviews or uploaded documents, so the archive has none.
+Passwords, two-factor secrets and key hashes are never included.
+`
+
+type PersonalDataHandler struct {
+	BaseHandler
+	Helper
+	repository models.PersonalDataRepository
+}
+
+// GetErasureRequests lists erasure requests oldest first, the status query
+// parameter narrows the list, for example to pending
+func (h *PersonalDataHandler) GetErasureRequests(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	status := ctx.Query("status")
+	switch status {
+	case "", models.ErasurePending, models.ErasureApproved, models.ErasureRejected, models.ErasureCancelled:
+	default:
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid status, use pending, approved, rejected or cancelled")
+	}
+
+	requests, err := h.repository.GetErasureRequests(context, status, h.ParsePageQuery(ctx))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", requests)
+}
+
+// ApproveErasureRequest anonymises the account right away, this cannot be
+// undone
+func (h *PersonalDataHandler) ApproveErasureRequest(ctx *fiber.Ctx) error {
+	return h.reviewErasureRequest(ctx, h.repository.ApproveErasureRequest, "Account erased")
+}
+
+func (h *PersonalDataHandler) RejectErasureRequest(ctx *fiber.Ctx) error {
+	return h.reviewErasureRequest(ctx, h.repository.RejectErasureRequest, "Erasure request rejected")
+}
+
+func (h *PersonalDataHandler) reviewErasureRequest(ctx *fiber.Ctx, review func(ctx context.Context, requestId uuid.UUID, reviewedBy uuid.UUID, note string) error, message string) error {
+	context, cancel := h.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	requestId, err := h.ParseUUID(ctx.Params("requestId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid erasure request ID format")
+	}
+
+	adminId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	formData := &models.ErasureReviewForm{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(formData); err != nil {
+			return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+		}
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		userValidator := validators.NewUserValidator()
+		return h.handleValidationError(ctx, err, &userValidator)
+	}
+
+	if err := review(context, requestId, adminId, formData.Note); err != nil {
+		if errors.Is(err, models.ErrErasureNotFound) {
+			return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+		}
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, message, nil)
+}
+
+// personalDataArchive zips the personal data as data.json with a README
+func personalDataArchive(data *models.PersonalData) ([]byte, error) {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	archive := zip.NewWriter(buffer)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"README.txt", []byte(personalDataReadme)},
+		{"data.json", content},
+	}
+	for _, file := range files {
+		writer, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: data.ExportedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(file.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func NewPersonalDataHandler(router fiber.Router, repository models.PersonalDataRepository, authorizer *middlewares.Authorizer) {
+	handler := &PersonalDataHandler{
+		repository: repository,
+	}
+
+	authorizer.Get(router, "/", policy.PermUserManage, handler.GetErasureRequests)
+	authorizer.Post(router, "/:requestId/approve", policy.PermUserManage, handler.ApproveErasureRequest)
+	authorizer.Post(router, "/:requestId/reject", policy.PermUserManage, handler.RejectErasureRequest)
+}
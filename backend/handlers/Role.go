@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/DestaAri1/RentAuto/middlewares"
 	"github.com/DestaAri1/RentAuto/models"
 	"github.com/DestaAri1/RentAuto/policy"
 	validators "github.com/DestaAri1/RentAuto/validatiors"
@@ -14,25 +16,15 @@ import (
 
 type RoleRepository struct {
 	BaseHandler
-	repository  models.RoleRepository
-	adminPolicy *policy.AdminPolicy  // Perbaiki nama field
+	Helper
+	repository models.RoleRepository
+	basePolicy *policy.Policy
 }
 
 func (h *RoleRepository) GetRoles(ctx *fiber.Ctx) error {
-	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second))
+	context, cancel := context.WithTimeout(ctx.UserContext(), time.Duration(5*time.Second))
 	defer cancel()
 
-	// Get role ID from context (assuming it's set by middleware)
-	roleId, ok := ctx.Locals("roleId").(uuid.UUID)
-	if !ok {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, "Role ID not found in context")
-	}
-
-	// Check permission untuk melihat roles (hanya admin)
-	if err := h.adminPolicy.CanManageRoles(context, roleId); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to view roles")
-	}
-
 	data, err := h.repository.GetRoles(context)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
@@ -41,20 +33,30 @@ func (h *RoleRepository) GetRoles(ctx *fiber.Ctx) error {
 	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", data)
 }
 
-func (h *RoleRepository) CreateRole(ctx *fiber.Ctx) error {
-	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second))
+// GetEffectivePermissions shows what a role grants once inheritance is resolved
+func (h *RoleRepository) GetEffectivePermissions(ctx *fiber.Ctx) error {
+	context, cancel := context.WithTimeout(ctx.UserContext(), time.Duration(5*time.Second))
 	defer cancel()
 
-	// Get role ID from context (assuming it's set by middleware)
-	roleId, ok := ctx.Locals("roleId").(uuid.UUID)
-	if !ok {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, "Role ID not found in context")
+	roleId, err := uuid.Parse(ctx.Params("roleId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid role ID format")
 	}
 
-	// Check permission untuk manage roles (hanya admin)
-	if err := h.adminPolicy.CanManageRoles(context, roleId); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to create roles")
+	effective, err := h.basePolicy.DescribeRole(context, roleId)
+	if errors.Is(err, policy.ErrRoleNotFound) {
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
 	}
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", effective)
+}
+
+func (h *RoleRepository) CreateRole(ctx *fiber.Ctx) error {
+	context, cancel := context.WithTimeout(ctx.UserContext(), time.Duration(5*time.Second))
+	defer cancel()
 
 	formData := &models.FormRole{}
 	if err := ctx.BodyParser(formData); err != nil {
@@ -66,8 +68,23 @@ func (h *RoleRepository) CreateRole(ctx *fiber.Ctx) error {
 		return h.handleValidationError(ctx, err, &roleValidator)
 	}
 
+	permissions, err := policy.NormalizePermissions(formData.Permission)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	formData.Permission = permissions
+
+	callerRoleId, err := h.GetRoleID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	if err := h.checkGrantable(context, callerRoleId, formData); err != nil {
+		return h.handlerError(ctx, err.Code, err.Message)
+	}
+
 	// Call repository method and check for error
-	err := h.repository.CreateRole(context, formData)
+	err = h.repository.CreateRole(context, formData)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
 	}
@@ -76,20 +93,9 @@ func (h *RoleRepository) CreateRole(ctx *fiber.Ctx) error {
 }
 
 func (h *RoleRepository) UpdateRole(ctx *fiber.Ctx) error {
-	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second))
+	context, cancel := context.WithTimeout(ctx.UserContext(), time.Duration(5*time.Second))
 	defer cancel()
 
-	// Get role ID from context (assuming it's set by middleware)
-	roleId, ok := ctx.Locals("roleId").(uuid.UUID)
-	if !ok {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, "Role ID not found in context")
-	}
-
-	// Check permission untuk manage roles (hanya admin)
-	if err := h.adminPolicy.CanManageRoles(context, roleId); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to update roles")
-	}
-
 	// Parse role ID from params
 	roleIdParam := ctx.Params("roleId")
 	roleIdToUpdate, err := uuid.Parse(roleIdParam)
@@ -107,6 +113,30 @@ func (h *RoleRepository) UpdateRole(ctx *fiber.Ctx) error {
 		return h.handleValidationError(ctx, err, &roleValidator)
 	}
 
+	permissions, err := policy.NormalizePermissions(formData.Permission)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	formData.Permission = permissions
+
+	callerRoleId, err := h.GetRoleID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	if roleIdToUpdate == callerRoleId {
+		return h.handlerError(ctx, fiber.StatusForbidden, "You cannot edit your own role")
+	}
+
+	// Roles that grant more than the caller holds are out of reach as well
+	if err := checkAssignableRole(context, h.basePolicy, callerRoleId, roleIdToUpdate); err != nil {
+		return h.handlerError(ctx, err.Code, err.Message)
+	}
+
+	if err := h.checkGrantable(context, callerRoleId, formData); err != nil {
+		return h.handlerError(ctx, err.Code, err.Message)
+	}
+
 	// Call repository method and check for error
 	err = h.repository.UpdateRole(context, formData, roleIdToUpdate)
 	if err != nil {
@@ -117,20 +147,9 @@ func (h *RoleRepository) UpdateRole(ctx *fiber.Ctx) error {
 }
 
 func (h *RoleRepository) DeleteRole(ctx *fiber.Ctx) error {
-	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second))
+	context, cancel := context.WithTimeout(ctx.UserContext(), time.Duration(5*time.Second))
 	defer cancel()
 
-	// Get role ID from context (assuming it's set by middleware)
-	roleId, ok := ctx.Locals("roleId").(uuid.UUID)
-	if !ok {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, "Role ID not found in context")
-	}
-
-	// Check permission untuk manage roles (hanya admin)
-	if err := h.adminPolicy.CanManageRoles(context, roleId); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to delete roles")
-	}
-
 	// Parse role ID from params
 	roleIdParam := ctx.Params("roleId")
 	roleIdToDelete, err := uuid.Parse(roleIdParam)
@@ -145,15 +164,35 @@ func (h *RoleRepository) DeleteRole(ctx *fiber.Ctx) error {
 	return h.handlerSuccess(ctx, fiber.StatusOK, "Successfully deleted role", nil)
 }
 
+// checkGrantable refuses a role that would grant more than the caller holds,
+// through its own permissions or through the roles it inherits from
+func (h *RoleRepository) checkGrantable(ctx context.Context, callerRoleId uuid.UUID, formData *models.FormRole) *fiber.Error {
+	allowed, err := h.basePolicy.HoldsAll(ctx, callerRoleId, formData.Permission)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, err.Error())
+	}
+	if !allowed {
+		return fiber.NewError(fiber.StatusForbidden, "You can only grant permissions you hold")
+	}
+
+	for _, parentId := range formData.Parents {
+		if err := checkAssignableRole(ctx, h.basePolicy, callerRoleId, parentId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Constructor yang diperbaiki
-func NewRoleHandler(router fiber.Router, repository models.RoleRepository, adminPolicy *policy.AdminPolicy) {
+func NewRoleHandler(router fiber.Router, repository models.RoleRepository, authorizer *middlewares.Authorizer, basePolicy *policy.Policy) {
 	handler := &RoleRepository{
-		repository:  repository,
-		adminPolicy: adminPolicy,  // Inject AdminPolicy
+		repository: repository,
+		basePolicy: basePolicy,
 	}
 
-	router.Get("/", handler.GetRoles)
-	router.Post("/", handler.CreateRole)
-	router.Patch("/:roleId", handler.UpdateRole)
-	router.Delete("/:roleId", handler.DeleteRole)
+	authorizer.Get(router, "/", policy.PermRoleManage, handler.GetRoles)
+	authorizer.Post(router, "/", policy.PermRoleManage, handler.CreateRole)
+	authorizer.Get(router, "/:roleId/permissions", policy.PermRoleManage, handler.GetEffectivePermissions)
+	authorizer.Patch(router, "/:roleId", policy.PermRoleManage, handler.UpdateRole)
+	authorizer.Delete(router, "/:roleId", policy.PermRoleManage, handler.DeleteRole)
 }
\ No newline at end of file
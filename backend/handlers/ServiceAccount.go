@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/DestaAri1/RentAuto/middlewares"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/policy"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ServiceAccountHandler struct {
+	BaseHandler
+	Helper
+	service    models.APIKeyServices
+	basePolicy *policy.Policy
+}
+
+func (h *ServiceAccountHandler) GetServiceAccounts(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	accounts, err := h.service.ListServiceAccounts(context)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", accounts)
+}
+
+func (h *ServiceAccountHandler) CreateServiceAccount(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	formData := &models.ServiceAccountForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Name and role are required")
+	}
+
+	roleId, err := h.GetRoleID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	if err := checkAssignableRole(context, h.basePolicy, roleId, formData.Role); err != nil {
+		return h.handlerError(ctx, err.Code, err.Message)
+	}
+
+	account, err := h.service.CreateServiceAccount(context, formData)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusCreated, "Service account created", account)
+}
+
+func (h *ServiceAccountHandler) DeleteServiceAccount(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	accountId, err := h.ParseUUID(ctx.Params("accountId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid service account ID format")
+	}
+
+	if err := h.service.DeleteServiceAccount(context, accountId); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Service account deleted", nil)
+}
+
+func (h *ServiceAccountHandler) GetAPIKeys(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	accountId, err := h.ParseUUID(ctx.Params("accountId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid service account ID format")
+	}
+
+	if _, err := h.service.GetServiceAccount(context, accountId); err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, "Service account not found")
+	}
+
+	keys, err := h.service.ListKeys(context, accountId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", keys)
+}
+
+func (h *ServiceAccountHandler) CreateAPIKey(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	accountId, err := h.ParseUUID(ctx.Params("accountId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid service account ID format")
+	}
+
+	if _, err := h.service.GetServiceAccount(context, accountId); err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, "Service account not found")
+	}
+
+	formData := &models.APIKeyForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Name and at least one scope are required, expiry must be between 1 and 365 days")
+	}
+
+	created, err := h.service.CreateKey(context, accountId, userId, formData)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusCreated, "API key created, copy it now as it will not be shown again", created)
+}
+
+func (h *ServiceAccountHandler) RevokeAPIKey(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	accountId, err := h.ParseUUID(ctx.Params("accountId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid service account ID format")
+	}
+
+	keyId, err := h.ParseUUID(ctx.Params("keyId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid API key ID format")
+	}
+
+	if err := h.service.RevokeKey(context, accountId, keyId); err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "API key revoked", nil)
+}
+
+func NewServiceAccountHandler(router fiber.Router, service models.APIKeyServices, authorizer *middlewares.Authorizer, basePolicy *policy.Policy) {
+	handler := &ServiceAccountHandler{
+		service:    service,
+		basePolicy: basePolicy,
+	}
+
+	authorizer.Get(router, "/", policy.PermUserManage, handler.GetServiceAccounts)
+	authorizer.Post(router, "/", policy.PermUserManage, handler.CreateServiceAccount)
+	authorizer.Delete(router, "/:accountId", policy.PermUserManage, handler.DeleteServiceAccount)
+	authorizer.Get(router, "/:accountId/api-keys", policy.PermUserManage, handler.GetAPIKeys)
+	authorizer.Post(router, "/:accountId/api-keys", policy.PermUserManage, handler.CreateAPIKey)
+	authorizer.Delete(router, "/:accountId/api-keys/:keyId", policy.PermUserManage, handler.RevokeAPIKey)
+}
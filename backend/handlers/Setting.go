@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/middlewares"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/policy"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SettingHandler struct {
+	BaseHandler
+	Helper
+	service models.SettingsServices
+}
+
+func (h *SettingHandler) GetSettings(ctx *fiber.Ctx) error {
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", h.service.ListSettings())
+}
+
+func (h *SettingHandler) GetSetting(ctx *fiber.Ctx) error {
+	setting, err := h.service.GetSetting(ctx.Params("key"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", setting)
+}
+
+func (h *SettingHandler) UpdateSetting(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	formData := &models.SettingForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if formData.Value == nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Value is required")
+	}
+
+	setting, err := h.service.UpdateSetting(context, ctx.Params("key"), formData.Value, userId)
+	if errors.Is(err, models.ErrUnknownSetting) {
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Setting updated", setting)
+}
+
+// NewSettingHandler exposes the runtime settings to roles that pass
+// AdminPolicy.CanManageSystemSettings, the system.settings permission
+func NewSettingHandler(router fiber.Router, service models.SettingsServices, authorizer *middlewares.Authorizer) {
+	handler := &SettingHandler{
+		service: service,
+	}
+
+	authorizer.Get(router, "/", policy.PermSystemSettings, handler.GetSettings)
+	authorizer.Get(router, "/:key", policy.PermSystemSettings, handler.GetSetting)
+	authorizer.Patch(router, "/:key", policy.PermSystemSettings, handler.UpdateSetting)
+}
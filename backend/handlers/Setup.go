@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	validators "github.com/DestaAri1/RentAuto/validatiors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SetupHandler struct {
+	BaseHandler
+	Helper
+	service models.SetupServices
+}
+
+// GetSetupStatus tells the frontend whether to show the setup page
+func (h *SetupHandler) GetSetupStatus(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	required, err := h.service.Required(context)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", fiber.Map{
+		"required": required,
+	})
+}
+
+// CompleteSetup creates the first super administrator with the setup token
+// from the server log, afterwards it always answers 410
+func (h *SetupHandler) CompleteSetup(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	formData := &models.SetupForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		userValidator := validators.NewUserValidator()
+		return h.handleValidationError(ctx, err, &userValidator)
+	}
+
+	user, err := h.service.Complete(context, formData)
+	switch {
+	case errors.Is(err, models.ErrSetupCompleted):
+		return h.handlerError(ctx, fiber.StatusGone, err.Error())
+	case errors.Is(err, models.ErrInvalidSetupToken):
+		return h.handlerError(ctx, fiber.StatusForbidden, err.Error())
+	case err != nil:
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Setup completed, sign in with your new account", fiber.Map{
+		"id":    user.ID,
+		"email": user.Email,
+	})
+}
+
+func NewSetupHandler(router fiber.Router, service models.SetupServices) {
+	handler := &SetupHandler{
+		service: service,
+	}
+
+	router.Get("/", handler.GetSetupStatus)
+	router.Post("/", handler.CompleteSetup)
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"strconv"
 	"time"
 
+	"github.com/DestaAri1/RentAuto/middlewares"
 	"github.com/DestaAri1/RentAuto/models"
 	"github.com/DestaAri1/RentAuto/policy"
 	validators "github.com/DestaAri1/RentAuto/validatiors"
@@ -14,24 +17,29 @@ import (
 type UserHandler struct {
 	BaseHandler
 	repository models.UserRepository
-	adminPolicy *policy.AdminPolicy
+	sessions models.SessionServices
+	userPermissions models.UserPermissionRepository
+	roles models.RoleRepository
+	invitations models.InvitationServices
+	profiles models.CustomerProfileRepository
+	eligibility models.EligibilityServices
+	basePolicy *policy.Policy
 	Helper
 }
 
+// GetAllUser lists users a page at a time. Query parameters: search, role,
+// verified, status, created_from and created_to in RFC 3339, sort (name, email or
+// created_at), order (asc or desc), page and limit.
 func (h *UserHandler) GetAllUser(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 * time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
 	defer cancel()
 
-	roleId, err := h.GetRoleID(ctx)
+	filter, err := h.parseUserFilter(ctx)
 	if err != nil {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
-	}
-
-	if err := h.adminPolicy.CanManageUsers(context, roleId); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to view roles")
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	users, err := h.repository.GetAllUser(context)
+	users, err := h.repository.GetAllUser(context, filter, h.ParsePageQuery(ctx))
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
 	}
@@ -39,19 +47,72 @@ func (h *UserHandler) GetAllUser(ctx *fiber.Ctx) error {
 	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", users)
 }
 
+// parseUserFilter reads the filter and sort parameters shared by the user
+// listing and the CSV export
+func (h *UserHandler) parseUserFilter(ctx *fiber.Ctx) (*models.UserFilter, error) {
+	filter := &models.UserFilter{
+		Search: ctx.Query("search"),
+		Sort:   ctx.Query("sort", "created_at"),
+		Desc:   ctx.Query("order", "desc") == "desc",
+	}
+
+	if _, ok := models.UserSortColumns[filter.Sort]; !ok {
+		return nil, errors.New("Invalid sort field, use name, email or created_at")
+	}
+
+	if order := ctx.Query("order"); order != "" && order != "asc" && order != "desc" {
+		return nil, errors.New("Invalid order, use asc or desc")
+	}
+
+	if role := ctx.Query("role"); role != "" {
+		roleId, err := h.ParseUUID(role)
+		if err != nil {
+			return nil, errors.New("Invalid role ID format")
+		}
+		filter.RoleID = roleId
+	}
+
+	if verified := ctx.Query("verified"); verified != "" {
+		value, err := strconv.ParseBool(verified)
+		if err != nil {
+			return nil, errors.New("Invalid verified value, use true or false")
+		}
+		filter.Verified = &value
+	}
+
+	if status := ctx.Query("status"); status != "" {
+		if status != models.UserStatusActive && status != models.UserStatusSuspended && status != models.UserStatusBlacklisted {
+			return nil, errors.New("Invalid status, use active, suspended or blacklisted")
+		}
+		filter.Status = status
+	}
+
+	createdFrom, err := parseTimeQuery(ctx, "created_from")
+	if err != nil {
+		return nil, errors.New("Invalid created_from time, use RFC 3339")
+	}
+	filter.CreatedFrom = createdFrom
+
+	createdTo, err := parseTimeQuery(ctx, "created_to")
+	if err != nil {
+		return nil, errors.New("Invalid created_to time, use RFC 3339")
+	}
+	filter.CreatedTo = createdTo
+
+	return filter, nil
+}
+
+// CreateUser creates the user without a password and emails them an
+// invitation to choose one, admins never learn the password
 func (h *UserHandler) CreateUser(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 * time.Second)
+	context, cancel := h.WithTimeout(ctx, 10 * time.Second)
 	defer cancel()
 
-	roleId, err := h.GetRoleID(ctx)
+	adminId, err := h.GetUserID(ctx)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
 	}
 
-	if err := h.adminPolicy.CanManageUsers(context, roleId); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to view roles")
-	}
-
 	formData := &models.CreateUserForm{}
 	if err := ctx.BodyParser(formData); err != nil {
 		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
@@ -62,34 +123,42 @@ func (h *UserHandler) CreateUser(ctx *fiber.Ctx) error {
 		return h.handleValidationError(ctx, err, &userValidator)
 	}
 
-	res := h.repository.CreateUser(context, formData)
-	if res != nil {
+	roleId, err := h.GetRoleID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	if err := checkAssignableRole(context, h.basePolicy, roleId, formData.Role); err != nil {
+		return h.handlerError(ctx, err.Code, err.Message)
+	}
+
+	user, err := h.repository.CreateUser(context, formData)
+	if err != nil {
 		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	return h.handlerSuccess(ctx, fiber.StatusOK, "Success create a user", nil)
+	// The user exists at this point, a failed email can be resent from the
+	// pending invitations
+	if err := h.invitations.Invite(context, user, adminId); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, "User created but the invitation could not be sent: "+err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success create a user, an invitation was sent to "+user.Email, fiber.Map{
+		"id": user.ID,
+	})
 }
 
 func (h *UserHandler) UpdateUser(ctx *fiber.Ctx) error {
-	context, cancel := h.WithTimeout(5 * time.Second)
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
 	defer cancel()
 
 	
-	roleId, err := h.GetRoleID(ctx)
-	if err != nil {
-		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
-	}
-
 	userIdParam := ctx.Params("userId")
 	userId, err := uuid.Parse(userIdParam)
 	if err != nil {
 		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid role ID format")
 	}
 	
-	if err := h.adminPolicy.CanManageUsers(context, roleId); err != nil {
-		return h.handlerError(ctx, fiber.StatusForbidden, "You don't have permission to view roles")
-	}
-
 	formData := &models.UpdateUserForm{}
 	if err := ctx.BodyParser(formData); err != nil {
 		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
@@ -110,14 +179,22 @@ func (h *UserHandler) UpdateUser(ctx *fiber.Ctx) error {
 		updateData["email"] = formData.Email
 	}
 
-	if formData.Password != "" {
-		updateData["password"] = formData.Password
-	}
-
 	if formData.Role != uuid.Nil{
+		roleId, err := h.GetRoleID(ctx)
+		if err != nil {
+			return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+		}
+
+		if err := checkAssignableRole(context, h.basePolicy, roleId, formData.Role); err != nil {
+			return h.handlerError(ctx, err.Code, err.Message)
+		}
 		updateData["role_id"] = formData.Role
 	}
 
+	if formData.Branch != nil {
+		updateData["branch"] = *formData.Branch
+	}
+
 	err = h.repository.UpdateUser(context, updateData, userId)
 
 	if err != nil {
@@ -127,13 +204,399 @@ func (h *UserHandler) UpdateUser(ctx *fiber.Ctx) error {
 	return h.handlerSuccess(ctx, fiber.StatusOK, "Success update user!", nil)
 }
 
-func NewUserHandler(router fiber.Router, repository models.UserRepository, policy *policy.AdminPolicy) {
+// DeleteUser soft deletes the user and logs them out everywhere, the user can
+// be restored until they are purged
+func (h *UserHandler) DeleteUser(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	currentUserId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if userId == currentUserId {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "You cannot delete your own account")
+	}
+
+	if err := h.repository.DeleteUser(context, userId); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if _, err := h.sessions.RevokeAllSessions(context, userId); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success delete user!", nil)
+}
+
+// SetUserStatus suspends, blacklists or reactivates the user. Suspended users
+// are refused at login and their existing tokens stop working.
+func (h *UserHandler) SetUserStatus(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	adminId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if userId == adminId {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "You cannot change the status of your own account")
+	}
+
+	formData := &models.UserStatusForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		userValidator := validators.NewUserValidator()
+		return h.handleValidationError(ctx, err, &userValidator)
+	}
+
+	if formData.ExpiresAt != nil && !formData.ExpiresAt.After(time.Now()) {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Expiry must be in the future")
+	}
+
+	if err := h.repository.SetUserStatus(context, userId, formData, adminId); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "User status updated", nil)
+}
+
+func (h *UserHandler) GetUserProfile(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	profile, err := h.profiles.GetProfile(context, userId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", profile)
+}
+
+func (h *UserHandler) UpdateUserProfile(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	return saveProfileForm(ctx, &h.BaseHandler, context, h.profiles, userId)
+}
+
+func (h *UserHandler) CheckUserEligibility(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	start, end, err := parseRentalPeriod(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	eligibility, err := h.eligibility.CheckEligibility(context, userId, start, end)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", eligibility)
+}
+
+func (h *UserHandler) GetDeletedUsers(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	users, err := h.repository.GetDeletedUsers(context, h.ParsePageQuery(ctx))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", users)
+}
+
+func (h *UserHandler) RestoreUser(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	if err := h.repository.RestoreUser(context, userId); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success restore user!", nil)
+}
+
+// PurgeUser anonymises a deleted user for good, it cannot be undone
+func (h *UserHandler) PurgeUser(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 10 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	if err := h.repository.PurgeUser(context, userId); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success purge user!", nil)
+}
+
+func (h *UserHandler) GetUserSessions(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	sessions, err := h.sessions.ListSessions(context, userId, uuid.Nil)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", sessions)
+}
+
+// ForceLogout revokes every session of the user so all of their tokens stop working
+func (h *UserHandler) ForceLogout(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	revoked, err := h.sessions.RevokeAllSessions(context, userId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "User has been logged out", fiber.Map{
+		"revoked": revoked,
+	})
+}
+
+// SendPasswordReset emails the user a link to choose a new password, admins
+// never set passwords themselves
+func (h *UserHandler) SendPasswordReset(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 10 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	adminId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	err = h.invitations.SendPasswordReset(context, userId, adminId)
+	switch {
+	case errors.Is(err, models.ErrInvitationUserNotFound):
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrNoPasswordToReset):
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	case err != nil:
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "A password reset invitation was sent", nil)
+}
+
+func (h *UserHandler) RevokeUserSession(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	sessionId, err := h.ParseUUID(ctx.Params("sessionId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid session ID format")
+	}
+
+	if err := h.sessions.RevokeSession(context, userId, sessionId); err != nil {
+		return h.handlerError(ctx, fiber.StatusNotFound, err.Error())
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Session revoked", nil)
+}
+
+func (h *UserHandler) GetUserPermissions(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	permissions, err := h.userPermissions.GetUserPermissions(context, userId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	now := time.Now()
+	for _, permission := range permissions {
+		permission.Active = permission.IsActive(now)
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success", permissions)
+}
+
+// SetUserPermission grants or denies one permission to the user, replacing an
+// earlier override of the same permission. Admins can only grant what they
+// hold themselves.
+func (h *UserHandler) SetUserPermission(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	adminId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	roleId, err := h.GetRoleID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	formData := &models.UserPermissionForm{}
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handlerError(ctx, fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := validator.New().Struct(formData); err != nil {
+		userValidator := validators.NewUserValidator()
+		return h.handleValidationError(ctx, err, &userValidator)
+	}
+
+	permission, ok := policy.CanonicalPermission(formData.Permission)
+	if !ok {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Unknown permission: " + formData.Permission)
+	}
+
+	if formData.ExpiresAt != nil && !formData.ExpiresAt.After(time.Now()) {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Expiry time must be in the future")
+	}
+
+	if formData.Effect == models.PermissionGrant {
+		if err := h.basePolicy.CheckPermission(context, roleId, permission); err != nil {
+			return h.handlerError(ctx, fiber.StatusForbidden, "You can only grant permissions you hold")
+		}
+	}
+
+	userPermission := &models.UserPermission{
+		UserID:     userId,
+		Permission: permission,
+		Effect:     formData.Effect,
+		ExpiresAt:  formData.ExpiresAt,
+		CreatedBy:  adminId,
+	}
+
+	if err := h.userPermissions.SaveUserPermission(context, userPermission); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	userPermission.Active = true
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "User permission saved", userPermission)
+}
+
+func (h *UserHandler) DeleteUserPermission(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 5 * time.Second)
+	defer cancel()
+
+	userId, err := h.ParseUUID(ctx.Params("userId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid user ID format")
+	}
+
+	permissionId, err := h.ParseUUID(ctx.Params("permissionId"))
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid permission ID format")
+	}
+
+	deleted, err := h.userPermissions.DeleteUserPermission(context, userId, permissionId)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+	if deleted == 0 {
+		return h.handlerError(ctx, fiber.StatusNotFound, "User permission not found")
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "User permission removed", nil)
+}
+
+func NewUserHandler(router fiber.Router, repository models.UserRepository, sessions models.SessionServices, userPermissions models.UserPermissionRepository, roles models.RoleRepository, invitations models.InvitationServices, profiles models.CustomerProfileRepository, eligibility models.EligibilityServices, authorizer *middlewares.Authorizer, basePolicy *policy.Policy) {
 	handler := &UserHandler{
 		repository: repository,
-		adminPolicy: policy,
+		sessions: sessions,
+		userPermissions: userPermissions,
+		roles: roles,
+		invitations: invitations,
+		profiles: profiles,
+		eligibility: eligibility,
+		basePolicy: basePolicy,
 	}
 
-	router.Get("/", handler.GetAllUser)
-	router.Post("/", handler.CreateUser)
-	router.Patch("/:userId", handler.UpdateUser)
+	authorizer.Get(router, "/", policy.PermUserManage, handler.GetAllUser)
+	authorizer.Post(router, "/", policy.PermUserManage, handler.CreateUser)
+	authorizer.Get(router, "/deleted", policy.PermUserManage, handler.GetDeletedUsers)
+	authorizer.Get(router, "/export", policy.PermUserManage, handler.ExportUsers)
+	authorizer.Post(router, "/import", policy.PermUserManage, handler.ImportUsers)
+	authorizer.Patch(router, "/:userId", policy.PermUserManage, handler.UpdateUser)
+	authorizer.Delete(router, "/:userId", policy.PermUserManage, handler.DeleteUser)
+	authorizer.Patch(router, "/:userId/status", policy.PermUserManage, handler.SetUserStatus)
+	authorizer.Get(router, "/:userId/profile", policy.PermUserManage, handler.GetUserProfile)
+	authorizer.Put(router, "/:userId/profile", policy.PermUserManage, handler.UpdateUserProfile)
+	authorizer.Get(router, "/:userId/eligibility", policy.PermUserManage, handler.CheckUserEligibility)
+	authorizer.Post(router, "/:userId/restore", policy.PermUserManage, handler.RestoreUser)
+	authorizer.Delete(router, "/:userId/purge", policy.PermUserManage, handler.PurgeUser)
+	authorizer.Get(router, "/:userId/sessions", policy.PermUserManage, handler.GetUserSessions)
+	authorizer.Post(router, "/:userId/password-reset", policy.PermUserManage, handler.SendPasswordReset)
+	authorizer.Delete(router, "/:userId/sessions", policy.PermUserManage, handler.ForceLogout)
+	authorizer.Delete(router, "/:userId/sessions/:sessionId", policy.PermUserManage, handler.RevokeUserSession)
+	authorizer.Get(router, "/:userId/permissions", policy.PermUserManage, handler.GetUserPermissions)
+	authorizer.Post(router, "/:userId/permissions", policy.PermUserManage, handler.SetUserPermission)
+	authorizer.Delete(router, "/:userId/permissions/:permissionId", policy.PermUserManage, handler.DeleteUserPermission)
 }
\ No newline at end of file
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	validators "github.com/DestaAri1/RentAuto/validatiors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	userImportMaxSize  = 1024 * 1024 // 1MB
+	userImportMaxRows  = 1000
+	userExportBatch    = 500
+	userImportRequired = "name, email and role"
+)
+
+var userExportHeader = []string{"id", "name", "email", "role", "branch", "email_verified_at", "created_at"}
+
+// ImportUsers creates users from an uploaded CSV file with the columns name,
+// email, role and optionally branch, role holds the name of the role. Every
+// row is validated first and nothing is created unless all rows are valid.
+// Query parameters: dry_run only returns the report, invite emails every
+// created user a link to choose their password.
+func (h *UserHandler) ImportUsers(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	dryRun, err := parseBoolQuery(ctx, "dry_run")
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid dry_run value, use true or false")
+	}
+
+	invite, err := parseBoolQuery(ctx, "invite")
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "Invalid invite value, use true or false")
+	}
+
+	adminId, err := h.GetUserID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	adminRoleId, err := h.GetRoleID(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, "A CSV file is required in the file field")
+	}
+	if file.Size > userImportMaxSize {
+		return h.handlerError(ctx, fiber.StatusRequestEntityTooLarge, "The CSV file must be at most 1MB")
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	defer content.Close()
+
+	rows, err := readUserImport(content)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	roles, err := h.roles.GetRoles(context)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+	roleIds := map[string]uuid.UUID{}
+	for _, role := range roles {
+		roleIds[strings.ToLower(role.Name)] = role.ID
+	}
+	assignable := map[uuid.UUID]bool{}
+
+	emails := make([]string, 0, len(rows))
+	for _, row := range rows {
+		emails = append(emails, row.Email)
+	}
+	taken, err := h.repository.GetTakenEmails(context, emails)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	report := &models.UserImportReport{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Errors: []*models.UserImportRowResult{},
+	}
+	userValidator := validators.NewUserValidator()
+	validate := validator.New()
+	seen := map[string]int{}
+	users := make([]*models.User, 0, len(rows))
+	lines := make([]int, 0, len(rows))
+
+	for i, row := range rows {
+		// Line 1 is the header
+		result := &models.UserImportRowResult{Line: i + 2, Email: row.Email, Errors: map[string]string{}}
+
+		if err := validate.Struct(row); err != nil {
+			var fieldErrors validator.ValidationErrors
+			if !errors.As(err, &fieldErrors) {
+				return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+			}
+			for _, fieldError := range fieldErrors {
+				if message := userValidator.HandleFieldError(fieldError.Field(), fieldError.Tag(), fieldError.Param()); message != "" {
+					result.Errors[fieldError.Field()] = message
+				}
+			}
+		}
+
+		// The same names CreateUser refuses
+		if name := row.Name; name == "administrator" || name == "admin" || name == "user" {
+			result.Errors["Name"] = fmt.Sprintf("Cannot create %v", name)
+		}
+
+		email := strings.ToLower(row.Email)
+		if _, ok := result.Errors["Email"]; !ok && email != "" {
+			if taken[email] {
+				result.Errors["Email"] = "This email is already used"
+			} else if line, ok := seen[email]; ok {
+				result.Errors["Email"] = fmt.Sprintf("Email is already used on line %d", line)
+			}
+			seen[email] = result.Line
+		}
+
+		roleId, ok := roleIds[strings.ToLower(row.Role)]
+		if _, failed := result.Errors["Role"]; !failed && !ok {
+			result.Errors["Role"] = "Role not found"
+		} else if !failed {
+			allowed, checked := assignable[roleId]
+			if !checked {
+				allowed, err = h.basePolicy.CanAssignRole(context, adminRoleId, roleId)
+				if err != nil {
+					return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+				}
+				assignable[roleId] = allowed
+			}
+			if !allowed {
+				result.Errors["Role"] = "You can only grant permissions you hold"
+			}
+		}
+
+		if len(result.Errors) > 0 {
+			report.Errors = append(report.Errors, result)
+			continue
+		}
+
+		users = append(users, &models.User{
+			Name:   row.Name,
+			Email:  row.Email,
+			RoleID: roleId,
+			Branch: row.Branch,
+		})
+		lines = append(lines, result.Line)
+	}
+	report.Valid = len(users)
+
+	if dryRun {
+		return h.handlerSuccess(ctx, fiber.StatusOK, "Dry run completed, nothing was created", report)
+	}
+
+	if len(report.Errors) > 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "fail",
+			"message": "Validation error, nothing was created",
+			"data":    report,
+		})
+	}
+
+	if err := h.repository.ImportUsers(context, users); err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+	report.Created = len(users)
+
+	// The users exist at this point, a failed email is reported and can be
+	// sent again without failing the import
+	if invite {
+		for i, user := range users {
+			if err := h.invitations.Invite(context, user, adminId); err != nil {
+				report.InviteFailures = append(report.InviteFailures, &models.UserImportRowResult{
+					Line:   lines[i],
+					Email:  user.Email,
+					Errors: map[string]string{"Invitation": err.Error()},
+				})
+				continue
+			}
+			report.Invited++
+		}
+	}
+
+	return h.handlerSuccess(ctx, fiber.StatusOK, "Success import users", report)
+}
+
+// ExportUsers downloads the users matching the same filter and sort
+// parameters as the listing as CSV
+func (h *UserHandler) ExportUsers(ctx *fiber.Ctx) error {
+	context, cancel := h.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	filter, err := h.parseUserFilter(ctx)
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	if err := writer.Write(userExportHeader); err != nil {
+		return h.handlerError(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+
+	err = h.repository.ExportUsers(context, filter, userExportBatch, func(users []*models.UserResponse) error {
+		for _, user := range users {
+			verifiedAt := ""
+			if user.EmailVerifiedAt != nil {
+				verifiedAt = user.EmailVerifiedAt.Format(time.RFC3339)
+			}
+
+			record := []string{
+				user.ID.String(),
+				csvSafe(user.Name),
+				csvSafe(user.Email),
+				csvSafe(user.Role.Name),
+				csvSafe(user.Branch),
+				verifiedAt,
+				user.CreatedAt.Format(time.RFC3339),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return h.handlerError(ctx, fiber.StatusBadGateway, err.Error())
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return h.handlerError(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+
+	ctx.Attachment(fmt.Sprintf("users-%s.csv", time.Now().Format("20060102-150405")))
+	ctx.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	return ctx.Status(fiber.StatusOK).Send(buffer.Bytes())
+}
+
+// readUserImport parses the CSV, the header decides the column order
+func readUserImport(content io.Reader) ([]*models.UserImportRow, error) {
+	reader := csv.NewReader(content)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("The CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CSV: %v", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		// Spreadsheet programs like to start the file with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch name {
+		case "name", "email", "role", "branch":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("Unknown column %q, use %s and optionally branch", name, userImportRequired)
+		}
+	}
+	for _, name := range []string{"name", "email", "role"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("The CSV file needs the columns %s", userImportRequired)
+		}
+	}
+
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rows := []*models.UserImportRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: %v", err)
+		}
+
+		if len(rows) == userImportMaxRows {
+			return nil, fmt.Errorf("The CSV file must have at most %d users", userImportMaxRows)
+		}
+
+		rows = append(rows, &models.UserImportRow{
+			Name:   column(record, "name"),
+			Email:  column(record, "email"),
+			Role:   column(record, "role"),
+			Branch: column(record, "branch"),
+		})
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("The CSV file has no users")
+	}
+
+	return rows, nil
+}
+
+// csvSafe keeps spreadsheet programs from running a value as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func parseBoolQuery(ctx *fiber.Ctx, param string) (bool, error) {
+	value := ctx.Query(param)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/DestaAri1/RentAuto/models"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
@@ -58,6 +60,24 @@ func (h *AuthHandler) handleValidation(ctx *fiber.Ctx, err error) error {
 					message = fmt.Errorf("password field is required").Error()
 				}
 				return h.handleError(ctx, fiber.StatusBadRequest, message)
+			case "ChallengeToken" :
+				switch err.Tag() {
+				case "required":
+					message = fmt.Errorf("challenge token is required").Error()
+				}
+				return h.handleError(ctx, fiber.StatusBadRequest, message)
+			case "Code" :
+				switch err.Tag() {
+				case "required":
+					message = fmt.Errorf("verification code is required").Error()
+				}
+				return h.handleError(ctx, fiber.StatusBadRequest, message)
+			case "Phone", "IDNumber" :
+				switch err.Tag() {
+				case "max":
+					message = fmt.Errorf("%s must be at most %s characters", strings.ToLower(err.Field()), err.Param()).Error()
+				}
+				return h.handleError(ctx, fiber.StatusBadRequest, message)
 			}
 		}
 	}
@@ -78,12 +98,55 @@ func (h *AuthHandler) Login(ctx *fiber.Ctx) error {
 		return h.handleValidation(ctx, err)
 	}
 
-	token, user, err := h.service.Login(context, creds)
+	result, err := h.service.Login(context, creds, clientInfo(ctx))
 
+	if errors.Is(err, models.ErrAccountSuspended) {
+		return h.handleError(ctx, fiber.StatusForbidden, err.Error())
+	}
+	if errors.Is(err, models.ErrTooManyMFAAttempts) {
+		return h.handleError(ctx, fiber.StatusTooManyRequests, err.Error())
+	}
 	if err != nil {
 		return h.handleError(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
+	if result.MFARequired {
+		return h.handleSucces(ctx, fiber.StatusOK, "Two-factor verification required", result)
+	}
+
+	if result.MFASetupRequired {
+		return h.handleSucces(ctx, fiber.StatusOK, "Two-factor authentication must be set up", result)
+	}
+
+	data := &fiber.Map{
+		"token" : result.Token,
+		"user" : result.User,
+	}
+	return h.handleSucces(ctx, fiber.StatusOK, "Successfully logged in", data)
+}
+
+func (h *AuthHandler) VerifyMFA(ctx *fiber.Ctx) error {
+	formData := &models.MFAChallengeForm{}
+
+	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second))
+	defer cancel()
+
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handleError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := validate.Struct(formData); err != nil {
+		return h.handleValidation(ctx, err)
+	}
+
+	token, user, err := h.service.VerifyMFALogin(context, formData, clientInfo(ctx))
+	if errors.Is(err, models.ErrTooManyMFAAttempts) {
+		return h.handleError(ctx, fiber.StatusTooManyRequests, err.Error())
+	}
+	if err != nil {
+		return h.handleError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
 	data := &fiber.Map{
 		"token" : token,
 		"user" : user,
@@ -91,6 +154,61 @@ func (h *AuthHandler) Login(ctx *fiber.Ctx) error {
 	return h.handleSucces(ctx, fiber.StatusOK, "Successfully logged in", data)
 }
 
+func (h *AuthHandler) StartMFASetup(ctx *fiber.Ctx) error {
+	formData := &models.MFASetupForm{}
+
+	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second))
+	defer cancel()
+
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handleError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := validate.Struct(formData); err != nil {
+		return h.handleValidation(ctx, err)
+	}
+
+	enrollment, err := h.service.StartMFASetup(context, formData)
+	if errors.Is(err, models.ErrTooManyMFAAttempts) {
+		return h.handleError(ctx, fiber.StatusTooManyRequests, err.Error())
+	}
+	if err != nil {
+		return h.handleError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	return h.handleSucces(ctx, fiber.StatusOK, "Scan the secret with your authenticator app", enrollment)
+}
+
+func (h *AuthHandler) CompleteMFASetup(ctx *fiber.Ctx) error {
+	formData := &models.MFAChallengeForm{}
+
+	context, cancel := context.WithTimeout(context.Background(), time.Duration(5*time.Second))
+	defer cancel()
+
+	if err := ctx.BodyParser(formData); err != nil {
+		return h.handleError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := validate.Struct(formData); err != nil {
+		return h.handleValidation(ctx, err)
+	}
+
+	token, user, codes, err := h.service.CompleteMFASetup(context, formData, clientInfo(ctx))
+	if errors.Is(err, models.ErrTooManyMFAAttempts) {
+		return h.handleError(ctx, fiber.StatusTooManyRequests, err.Error())
+	}
+	if err != nil {
+		return h.handleError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	data := &fiber.Map{
+		"token" : token,
+		"user" : user,
+		"recovery_codes" : codes,
+	}
+	return h.handleSucces(ctx, fiber.StatusOK, "Two-factor authentication enabled", data)
+}
+
 func (h *AuthHandler) Register(ctx *fiber.Ctx) error {
 	creds := &models.AuthCredentials{}
 
@@ -105,8 +223,11 @@ func (h *AuthHandler) Register(ctx *fiber.Ctx) error {
 		return h.handleValidation(ctx, err)
 	}
 
-	token, user, err := h.service.Register(context, creds)
+	token, user, err := h.service.Register(context, creds, clientInfo(ctx))
 
+	if errors.Is(err, models.ErrBlacklisted) {
+		return h.handleError(ctx, fiber.StatusForbidden, err.Error())
+	}
 	if err != nil {
 		return h.handleError(ctx, fiber.StatusBadRequest, err.Error())
 	}
@@ -119,6 +240,84 @@ func (h *AuthHandler) Register(ctx *fiber.Ctx) error {
 	return h.handleSucces(ctx, fiber.StatusOK, "Successfully logged in", data)
 }
 
+const oidcStateCookie = "oidc_state"
+
+func (h *AuthHandler) OIDCProviders(ctx *fiber.Ctx) error {
+	return h.handleSucces(ctx, fiber.StatusOK, "Success", h.service.OIDCProviders())
+}
+
+func (h *AuthHandler) OIDCLogin(ctx *fiber.Ctx) error {
+	context, cancel := context.WithTimeout(context.Background(), time.Duration(10*time.Second))
+	defer cancel()
+
+	authorization, err := h.service.OIDCAuthorize(context, ctx.Params("provider"), uuid.Nil)
+	if err != nil {
+		return h.handleError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	// The signed state only needs to survive the round trip to the provider
+	ctx.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    authorization.StateToken,
+		Path:     "/api/auth/oidc",
+		MaxAge:   600,
+		HTTPOnly: true,
+		Secure:   ctx.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return ctx.Redirect(authorization.URL, fiber.StatusFound)
+}
+
+func (h *AuthHandler) OIDCCallback(ctx *fiber.Ctx) error {
+	context, cancel := context.WithTimeout(context.Background(), time.Duration(10*time.Second))
+	defer cancel()
+
+	if providerError := ctx.Query("error"); providerError != "" {
+		return h.handleError(ctx, fiber.StatusUnauthorized, "Login was cancelled by the identity provider: "+providerError)
+	}
+
+	stateToken := ctx.Cookies(oidcStateCookie)
+	ctx.ClearCookie(oidcStateCookie)
+
+	result, err := h.service.OIDCCallback(context, ctx.Params("provider"), ctx.Query("code"), ctx.Query("state"), stateToken, clientInfo(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrIdentityLinkRefused):
+			return h.handleError(ctx, fiber.StatusForbidden, err.Error())
+		case errors.Is(err, models.ErrIdentityLinked):
+			return h.handleError(ctx, fiber.StatusConflict, err.Error())
+		}
+		return h.handleError(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	if result.Linked {
+		return h.handleSucces(ctx, fiber.StatusOK, "Identity linked, you can now sign in with it", nil)
+	}
+
+	if result.MFARequired {
+		return h.handleSucces(ctx, fiber.StatusOK, "Two-factor verification required", result)
+	}
+
+	if result.MFASetupRequired {
+		return h.handleSucces(ctx, fiber.StatusOK, "Two-factor authentication must be set up", result)
+	}
+
+	data := &fiber.Map{
+		"token" : result.Token,
+		"user" : result.User,
+	}
+	return h.handleSucces(ctx, fiber.StatusOK, "Successfully logged in", data)
+}
+
+// clientInfo identifies the device a login comes from for its session
+func clientInfo(ctx *fiber.Ctx) models.ClientInfo {
+	return models.ClientInfo{
+		UserAgent : ctx.Get(fiber.HeaderUserAgent),
+		IPAddress : ctx.IP(),
+	}
+}
+
 func NewAuthHandler(router fiber.Router, service models.AuthServices) {
 	handler := &AuthHandler{
 		service:service,
@@ -126,4 +325,10 @@ func NewAuthHandler(router fiber.Router, service models.AuthServices) {
 
 	router.Post("/login", handler.Login)
 	router.Post("/register", handler.Register)
+	router.Post("/login/mfa", handler.VerifyMFA)
+	router.Post("/mfa/setup", handler.StartMFASetup)
+	router.Post("/mfa/setup/confirm", handler.CompleteMFASetup)
+	router.Get("/oidc/providers", handler.OIDCProviders)
+	router.Get("/oidc/:provider/login", handler.OIDCLogin)
+	router.Get("/oidc/:provider/callback", handler.OIDCCallback)
 }
\ No newline at end of file
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/policy"
 	"github.com/DestaAri1/RentAuto/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -13,8 +15,9 @@ import (
 
 type Helper struct {}
 
-func (hp *Helper) WithTimeout(duration time.Duration) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), duration)
+// WithTimeout derives a request context so values set by the middlewares reach the policies
+func (hp *Helper) WithTimeout(ctx *fiber.Ctx, duration time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx.UserContext(), duration)
 }
 
 // GetUserID extracts the user ID from the context
@@ -35,6 +38,32 @@ func (hp *Helper) GetRoleID(ctx *fiber.Ctx) (uuid.UUID, error) {
 	return roleId, nil
 }
 
+// GetSessionID extracts the session ID from the context
+func (hp *Helper) GetSessionID(ctx *fiber.Ctx) (uuid.UUID, error) {
+	sessionId, ok := ctx.Locals("sessionId").(uuid.UUID)
+	if !ok {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Session ID not found in context")
+	}
+	return sessionId, nil
+}
+
+// GetSubject describes the requesting user for policies that evaluate the loaded resource
+func (hp *Helper) GetSubject(ctx *fiber.Ctx) (policy.Subject, error) {
+	userId, err := hp.GetUserID(ctx)
+	if err != nil {
+		return policy.Subject{}, err
+	}
+
+	roleId, err := hp.GetRoleID(ctx)
+	if err != nil {
+		return policy.Subject{}, err
+	}
+
+	branch, _ := ctx.Locals("branch").(string)
+
+	return policy.Subject{UserID: userId, RoleID: roleId, Branch: branch}, nil
+}
+
 // ParseUUID parses a string parameter to UUID
 func(hp *Helper) ParseUUID(param string) (uuid.UUID, error) {
 	id, err := uuid.Parse(param)
@@ -44,6 +73,11 @@ func(hp *Helper) ParseUUID(param string) (uuid.UUID, error) {
 	return id, nil
 }
 
+// ParsePageQuery reads the page and limit query parameters of a listing
+func (hp *Helper) ParsePageQuery(ctx *fiber.Ctx) *models.PageQuery {
+	return models.NewPageQuery(ctx.QueryInt("page", 1), ctx.QueryInt("limit", models.DefaultPageLimit))
+}
+
 func (hp *Helper) ParseFormValue(ctx *fiber.Ctx, fieldName string, fieldType string, destFolder ...string) (any, error) {
 	value := ctx.FormValue(fieldName)
 
@@ -63,12 +97,12 @@ func (hp *Helper) ParseFormValue(ctx *fiber.Ctx, fieldName string, fieldType str
 		file, err := ctx.FormFile(fieldName)
 
 		contentType := file.Header.Get("Content-Type")
-		if !strings.Contains(utils.AllowedMimeTypes, contentType) {
+		if !utils.IsAllowedMimeType(contentType) {
 			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid file type for "+fieldName)
 		}
 
-		if file.Size > utils.MaxFileSize {
-			return nil, fiber.NewError(fiber.StatusBadRequest, "File size exceeds 5MB for "+fieldName)
+		if file.Size > utils.MaxFileSize() {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "File size exceeds "+utils.MaxFileSizeLabel()+" for "+fieldName)
 		}
 
 		// Gunakan destFolder jika disediakan, atau default
@@ -87,3 +121,18 @@ func (hp *Helper) ParseFormValue(ctx *fiber.Ctx, fieldName string, fieldType str
 		return nil, fiber.NewError(fiber.StatusBadRequest, "Unsupported field type for " + fieldName)
 	}
 }
+
+// checkAssignableRole is nil when roleId may hand out targetRoleId, otherwise
+// it carries the status and message to answer with
+func checkAssignableRole(ctx context.Context, basePolicy *policy.Policy, roleId uuid.UUID, targetRoleId uuid.UUID) *fiber.Error {
+	allowed, err := basePolicy.CanAssignRole(ctx, roleId, targetRoleId)
+	switch {
+	case errors.Is(err, policy.ErrRoleNotFound):
+		return fiber.NewError(fiber.StatusBadRequest, "Role not found")
+	case err != nil:
+		return fiber.NewError(fiber.StatusBadGateway, err.Error())
+	case !allowed:
+		return fiber.NewError(fiber.StatusForbidden, "You can only assign roles whose permissions you hold")
+	}
+	return nil
+}
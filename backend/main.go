@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
+	"strings"
+	"time"
 
+	"github.com/DestaAri1/RentAuto/config"
 	"github.com/DestaAri1/RentAuto/database"
 	"github.com/DestaAri1/RentAuto/handlers"
 	"github.com/DestaAri1/RentAuto/middlewares"
@@ -10,6 +14,7 @@ import (
 	"github.com/DestaAri1/RentAuto/policy"
 	"github.com/DestaAri1/RentAuto/repositories"
 	"github.com/DestaAri1/RentAuto/services"
+	"github.com/DestaAri1/RentAuto/utils"
 	"github.com/DestaAri1/RentAuto/validatiors"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -17,68 +22,168 @@ import (
 )
 
 // App configuration
-func setupApp() *fiber.App {
+func setupApp(cfg *config.Config) *fiber.App {
 	app := fiber.New(fiber.Config{
 		AppName:      "Rent Car",
 		ServerHeader: "Fiber",
 	})
 
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
+		AllowOrigins: strings.Join(cfg.Server.CORSOrigins, ", "),
 		AllowMethods: "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 	}))
 
-	app.Static("/assets", "./assets")
+	app.Static(cfg.Assets.URL, cfg.Assets.Dir)
+	utils.ConfigureAssets(cfg.Assets.CarDir)
 
 	return app
 }
 
 // Repository initialization
 type AppRepositories struct {
-	auth     models.AuthRepository
-	cars     models.CarRepository
-	carChild models.CarChildRepository
-	roles    models.RoleRepository
-	carTypes models.CarTypesRepository
-	users    models.UserRepository
+	auth      models.AuthRepository
+	cars      models.CarRepository
+	carChild  models.CarChildRepository
+	roles     models.RoleRepository
+	carTypes  models.CarTypesRepository
+	users     models.UserRepository
+	mfa       models.MFARepository
+	keys      models.SigningKeyRepository
+	sessions  models.SessionRepository
+	passwords models.PasswordRepository
+	apiKeys   models.APIKeyRepository
+
+	userPermissions models.UserPermissionRepository
+	auditLogs       models.AuditLogRepository
+	settings        models.SettingRepository
+	invitations     models.InvitationRepository
+	blacklist       models.BlacklistRepository
+	profiles        models.CustomerProfileRepository
+	personalData    models.PersonalDataRepository
+	setup           models.SetupRepository
 }
 
-func setupRepositories(database *gorm.DB) AppRepositories {
+func setupRepositories(cfg *config.Config, database *gorm.DB) AppRepositories {
+	mfaKey, err := utils.ParseSecretKey(cfg.Auth.MFAEncryptionKey)
+	if err != nil {
+		log.Fatalf("Invalid MFA encryption key: %v", err)
+	}
+
 	return AppRepositories{
-		auth:     repository.NewAuthRepository(database),
-		cars:     repository.NewCarRepository(database),
-		carChild: repository.NewCarChildRepository(database),
-		roles:    repository.NewRoleRepository(database),
-		carTypes: repository.NewCarTypeRepositories(database),
-		users:    repository.NewUserRepository(database),
+		auth:      repository.NewAuthRepository(database),
+		cars:      repository.NewCarRepository(database),
+		carChild:  repository.NewCarChildRepository(database),
+		roles:     repository.NewCachedRoleRepository(repository.NewRoleRepository(database), cfg.Auth.RoleCacheTTL.Duration),
+		carTypes:  repository.NewCarTypeRepositories(database),
+		users:     repository.NewUserRepository(database),
+		mfa:       repository.NewMFARepository(database, mfaKey),
+		keys:      repository.NewSigningKeyRepository(database),
+		sessions:  repository.NewSessionRepository(database),
+		passwords: repository.NewPasswordRepository(database),
+		apiKeys:   repository.NewAPIKeyRepository(database),
+
+		userPermissions: repository.NewCachedUserPermissionRepository(repository.NewUserPermissionRepository(database), cfg.Auth.RoleCacheTTL.Duration),
+		auditLogs:       repository.NewAuditLogRepository(database),
+		settings:        repository.NewSettingRepository(database),
+		invitations:     repository.NewInvitationRepository(database),
+		blacklist:       repository.NewBlacklistRepository(database),
+		profiles:        repository.NewCustomerProfileRepository(database),
+		personalData:    repository.NewPersonalDataRepository(database),
+		setup:           repository.NewSetupRepository(database),
 	}
 }
 
 // Service initialization
 type AppServices struct {
-	auth models.AuthServices
+	auth      models.AuthServices
+	keys      models.KeyManager
+	sessions  models.SessionServices
+	passwords models.PasswordServices
+	apiKeys   models.APIKeyServices
+	settings  models.SettingsServices
+	mailer    models.Mailer
+
+	invitations models.InvitationServices
+	eligibility models.EligibilityServices
+	setup       models.SetupServices
 }
 
-func setupServices(repos AppRepositories) AppServices {
+func setupServices(cfg *config.Config, repos AppRepositories, policies AppPolicies) AppServices {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	keyManager, err := services.NewKeyManager(ctx, repos.keys, cfg.Auth)
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	keyManager.StartRotation(context.Background())
+
+	sealed, err := repos.mfa.SealPlaintextSecrets(ctx)
+	if err != nil {
+		log.Fatalf("Failed to encrypt MFA secrets: %v", err)
+	}
+	if sealed > 0 {
+		log.Printf("Encrypted %d MFA secrets stored in plaintext", sealed)
+	}
+
+	sessionService := services.NewSessionService(repos.sessions)
+	sessionService.StartCleanup(context.Background(), time.Hour)
+
+	passwordService := services.NewPasswordService(repos.passwords, cfg.Auth.Password)
+
+	settingsService, err := services.NewSettingsService(ctx, repos.settings, services.SettingsSchema(cfg.Upload))
+	if err != nil {
+		log.Fatalf("Failed to load settings: %v", err)
+	}
+	settingsService.Subscribe(models.SettingUploadMaxFileSize, func(value interface{}) {
+		utils.SetMaxFileSize(value.(int64))
+	})
+	settingsService.Subscribe(models.SettingUploadAllowedMimeTypes, func(value interface{}) {
+		utils.SetAllowedMimeTypes(value.([]string))
+	})
+
+	mailer := services.NewMailer(cfg.Mail)
+
+	setupService, err := services.NewSetupService(ctx, repos.setup, passwordService)
+	if err != nil {
+		log.Fatalf("Failed to check setup: %v", err)
+	}
+
 	return AppServices{
-		auth: services.NewAuthService(repos.auth),
+		auth:      services.NewAuthService(repos.auth, repos.mfa, policies.admin, keyManager, sessionService, passwordService, repos.blacklist, cfg.Auth),
+		keys:      keyManager,
+		sessions:  sessionService,
+		passwords: passwordService,
+		apiKeys:   services.NewAPIKeyService(repos.apiKeys, repos.auth, policies.base),
+		settings:  settingsService,
+		mailer:    mailer,
+
+		invitations: services.NewInvitationService(repos.invitations, repos.auth, passwordService, sessionService, mailer, cfg.Server.PublicURL, cfg.Auth.InvitationTTL.Duration),
+		eligibility: services.NewEligibilityService(repos.profiles, repos.auth, repos.blacklist, settingsService),
+		setup:       setupService,
 	}
 }
 
 // Policy initialization
 type AppPolicies struct {
-	admin *policy.AdminPolicy
+	base     *policy.Policy
+	admin    *policy.AdminPolicy
+	cars     *policy.CarPolicy
+	carTypes *policy.CarTypesPolicy
 }
 
 func setupPolicies(repos AppRepositories) AppPolicies {
 	// Setup base policy
-	basePolicy := policy.NewPolicy(repos.roles) // Sesuaikan dengan constructor Policy Anda
+	basePolicy := policy.NewPolicy(repos.roles, repos.userPermissions) // Sesuaikan dengan constructor Policy Anda
 	
 	// Setup admin policy
 	adminPolicy := policy.NewAdminPolicy(basePolicy)
 
 	return AppPolicies{
-		admin: adminPolicy,
+		base:     basePolicy,
+		admin:    adminPolicy,
+		cars:     policy.NewCarPolicy(basePolicy),
+		carTypes: policy.NewCarTypesPolicy(basePolicy),
 	}
 }
 
@@ -88,48 +193,72 @@ func setupValidator(database *gorm.DB) *validators.ValidatorManager {
 }
 
 // Route setup
-func setupRoutes(app *fiber.App, database *gorm.DB, repos AppRepositories, services AppServices, policies AppPolicies, validatorManager *validators.ValidatorManager) {
+func setupRoutes(app *fiber.App, cfg *config.Config, database *gorm.DB, repos AppRepositories, services AppServices, policies AppPolicies, validatorManager *validators.ValidatorManager) {
+	// Records the permission of every route for the report below
+	authorizer := middlewares.NewAuthorizer(app, policies.base)
+
+	// Public signing keys
+	handlers.NewJWKSHandler(app.Group("/.well-known"), services.keys)
+
 	// API group
 	api := app.Group("/api")
 
 	// Public routes
+	handlers.NewSetupHandler(api.Group("/setup"), services.setup)
 	auth := api.Group("/auth")
 	handlers.NewAuthHandler(auth, services.auth)
+	handlers.NewInvitationHandler(auth.Group("/invitations"), services.invitations)
 	// handlers.NewUserProductHandler(api.Group("/product"), repos.userProduct)
 
 	// Protected routes
-	protected := api.Use(middlewares.AuthProtected(database))
+	protected := api.Use(middlewares.AuthProtected(database, repos.sessions, services.keys, services.apiKeys, repos.userPermissions))
 
 	// Public Protected Routes
 	//
 
 	//  User routes
-	//
+	handlers.NewAccountHandler(protected.Group("/account", middlewares.RequireSession()), services.auth, services.sessions, services.apiKeys, repos.profiles, services.eligibility, repos.personalData)
 	//  Admin & Other except User routes
-	handlers.NewRoleHandler(protected.Group("/admin/role"), repos.roles, policies.admin)
-	handlers.NewUserHandler(protected.Group("/admin/user-management"), repos.users, policies.admin)
-	handlers.NewCarHandler(protected.Group("/admin/cars"), repos.cars, repos.roles)
-	handlers.NewCarTypesHandler(protected.Group("/admin/car-types"), repos.carTypes, repos.roles, validatorManager)
-	handlers.NewCarChildHandler(protected.Group("/admin/cars/children"), repos.carChild, repos.roles)
+	handlers.NewPermissionHandler(protected.Group("/admin/permissions"), authorizer)
+	handlers.NewRoleHandler(protected.Group("/admin/role"), repos.roles, authorizer, policies.base)
+	handlers.NewServiceAccountHandler(protected.Group("/admin/service-accounts"), services.apiKeys, authorizer, policies.base)
+	handlers.NewUserHandler(protected.Group("/admin/user-management"), repos.users, services.sessions, repos.userPermissions, repos.roles, services.invitations, repos.profiles, services.eligibility, authorizer, policies.base)
+	handlers.NewCarHandler(protected.Group("/admin/cars"), repos.cars, authorizer, policies.cars)
+	handlers.NewCarTypesHandler(protected.Group("/admin/car-types"), repos.carTypes, authorizer, policies.carTypes, validatorManager)
+	handlers.NewCarChildHandler(protected.Group("/admin/cars/children"), repos.carChild, authorizer, policies.cars)
+	handlers.NewAuditLogHandler(protected.Group("/admin/audit-logs"), repos.auditLogs, authorizer)
+	handlers.NewSettingHandler(protected.Group("/admin/settings"), services.settings, authorizer)
+	handlers.NewImpersonationHandler(protected.Group("/admin/impersonation"), services.auth, authorizer)
+	handlers.NewBlacklistHandler(protected.Group("/admin/blacklist"), repos.blacklist, authorizer)
+	handlers.NewInvitationAdminHandler(protected.Group("/admin/invitations"), services.invitations, authorizer)
+	handlers.NewPersonalDataHandler(protected.Group("/admin/erasure-requests"), repos.personalData, authorizer)
 
 	//  Common routes
+
+	authorizer.LogRoutes(app)
 }
 
 func main() {
+	// Load and validate configuration before touching anything else
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Refusing to start: %v", err)
+	}
+
 	// Initialize components
-	database := database.Init(database.DBMigrator)
-	app := setupApp()
-	repositories := setupRepositories(database)
-	services := setupServices(repositories)
+	database := database.Init(cfg.Database, database.DBMigrator)
+	app := setupApp(cfg)
+	repositories := setupRepositories(cfg, database)
 	policies := setupPolicies(repositories) // Setup policies
+	services := setupServices(cfg, repositories, policies)
 	validatorManager := setupValidator(database)
 
 	// Setup routes
-	setupRoutes(app, database, repositories, services, policies, validatorManager)
+	setupRoutes(app, cfg, database, repositories, services, policies, validatorManager)
 
 	// Start server with more informative logging
-	log.Println("Server starting on http://localhost:3000")
-	if err := app.Listen("0.0.0.0:3000"); err != nil {
+	log.Printf("Server starting on %s (%s mode)", cfg.Server.ListenAddr, cfg.Env)
+	if err := app.Listen(cfg.Server.ListenAddr); err != nil {
 		log.Fatalf("Error starting server: %v", err)
 	}
 }
\ No newline at end of file
@@ -1,23 +1,21 @@
 package middlewares
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/DestaAri1/RentAuto/models"
-	"github.com/DestaAri1/RentAuto/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-var (
-	// Default JWT secret if not configured in environment
-	defaultJWTSecret = "rent-auto-secret-key-2024"
-)
+// sessionTouchInterval is how stale last_seen_at may get before it is updated
+const sessionTouchInterval = time.Minute
 
 func errorMiddleware(ctx *fiber.Ctx, status int, message string) error {
 	return ctx.Status(status).JSON(&fiber.Map{
@@ -26,7 +24,7 @@ func errorMiddleware(ctx *fiber.Ctx, status int, message string) error {
 	})
 }
 
-func AuthProtected(db *gorm.DB) fiber.Handler {
+func AuthProtected(db *gorm.DB, sessions models.SessionRepository, keyManager models.KeyManager, apiKeys models.APIKeyServices, userPermissions models.UserPermissionRepository) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		authHeader := ctx.Get("Authorization")
 		if authHeader == "" {
@@ -34,20 +32,18 @@ func AuthProtected(db *gorm.DB) fiber.Handler {
 		}
 
 		tokenParts := strings.Split(authHeader, " ")
+		if len(tokenParts) == 2 && tokenParts[0] == models.APIKeyScheme {
+			return apiKeyProtected(ctx, db, apiKeys, userPermissions, tokenParts[1])
+		}
+
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
 			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Invalid token format")
 		}
 
 		tokenString := tokenParts[1]
-		
-		// Get JWT secret from environment or use default
-		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			secret = defaultJWTSecret
-		}
 
-		// Use the ValidateToken function from utils
-		token, err := utils.ValidateToken(tokenString, secret)
+		// Verify signature, kid and algorithm with the key manager
+		token, err := keyManager.Parse(tokenString)
 		if err != nil {
 			return errorMiddleware(ctx, fiber.StatusUnauthorized, fmt.Sprintf("Invalid token: %v", err))
 		}
@@ -57,6 +53,11 @@ func AuthProtected(db *gorm.DB) fiber.Handler {
 			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Invalid token claims")
 		}
 
+		// MFA challenge tokens only unlock the second login step
+		if _, ok := claims["purpose"]; ok {
+			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Invalid token type")
+		}
+
 		// Check token expiration
 		exp, ok := claims["exp"].(float64)
 		if !ok {
@@ -88,6 +89,53 @@ func AuthProtected(db *gorm.DB) fiber.Handler {
 			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Invalid role ID format")
 		}
 
+		// Every access token is bound to a session that can be revoked server side
+		sessionIdStr, ok := claims["sid"].(string)
+		if !ok {
+			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Invalid session claim")
+		}
+
+		sessionId, err := uuid.Parse(sessionIdStr)
+		if err != nil {
+			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Invalid session ID format")
+		}
+
+		// Impersonation tokens name the support user in the imp claim
+		impersonatorId := uuid.Nil
+		if imp, ok := claims["imp"]; ok {
+			impStr, _ := imp.(string)
+			impersonatorId, err = uuid.Parse(impStr)
+			if err != nil {
+				return errorMiddleware(ctx, fiber.StatusUnauthorized, "Invalid impersonation claim")
+			}
+		}
+
+		session, err := sessions.GetSession(ctx.UserContext(), sessionId)
+		if err != nil || session.UserID != userId {
+			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Session not found")
+		}
+
+		now := time.Now()
+		if !session.IsActive(now) {
+			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Session expired or revoked")
+		}
+
+		// The claim must match the session so a token cannot claim or drop impersonation
+		sessionImpersonator := uuid.Nil
+		if session.ImpersonatorID != nil {
+			sessionImpersonator = *session.ImpersonatorID
+		}
+		if sessionImpersonator != impersonatorId {
+			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Invalid impersonation claim")
+		}
+
+		// Only write last seen once in a while to keep requests cheap
+		if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+			if err := sessions.TouchSession(ctx.UserContext(), sessionId, now); err != nil {
+				log.Printf("Failed to update last seen of session %s: %v", sessionId, err)
+			}
+		}
+
 		// Get user from database to verify role
 		var user models.User
 		if err := db.Preload("Role").First(&user, "id = ?", userId).Error; err != nil {
@@ -99,10 +147,128 @@ func AuthProtected(db *gorm.DB) fiber.Handler {
 			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Invalid role")
 		}
 
+		// Tokens issued before a suspension stop working right away
+		if user.StatusAt(now) == models.UserStatusSuspended {
+			return errorMiddleware(ctx, fiber.StatusForbidden, models.ErrAccountSuspended.Error())
+		}
+
 		// Set user ID and role ID in context
 		ctx.Locals("userId", userId)
 		ctx.Locals("roleId", roleId)
+		ctx.Locals("sessionId", sessionId)
+		ctx.Locals("branch", user.Branch)
+		ctx.SetUserContext(models.WithAuditActor(ctx.UserContext(), models.AuditActor{UserID: userId, ImpersonatorID: impersonatorId, IP: ctx.IP()}))
+
+		if err := loadPermissionOverrides(ctx, userPermissions, userId, now); err != nil {
+			return errorMiddleware(ctx, fiber.StatusInternalServerError, "Failed to load user permissions")
+		}
+
+		if impersonatorId == uuid.Nil {
+			return ctx.Next()
+		}
+
+		ctx.Locals("impersonatorId", impersonatorId)
+		err = ctx.Next()
+		recordImpersonatedRequest(ctx, db, userId, impersonatorId, err)
+		return err
+	}
+}
+
+// recordImpersonatedRequest writes every request made under impersonation to
+// the audit log, together with the response status
+func recordImpersonatedRequest(ctx *fiber.Ctx, db *gorm.DB, userId uuid.UUID, impersonatorId uuid.UUID, handlerErr error) {
+	// Errors returned by the handler are turned into a response only later
+	status := ctx.Response().StatusCode()
+	if handlerErr != nil {
+		status = fiber.StatusInternalServerError
+		var fiberErr *fiber.Error
+		if errors.As(handlerErr, &fiberErr) {
+			status = fiberErr.Code
+		}
+	}
+
+	detail := fmt.Sprintf("%s %s %d", ctx.Method(), ctx.OriginalURL(), status)
+	if len(detail) > 255 {
+		detail = detail[:255]
+	}
+
+	entry := &models.AuditLog{
+		ActorID:        &userId,
+		ImpersonatorID: &impersonatorId,
+		IP:             ctx.IP(),
+		Action:         models.AuditRequest,
+		Entity:         models.AuditEntityUser,
+		EntityID:       userId,
+		Detail:         detail,
+	}
+
+	if err := db.Create(entry).Error; err != nil {
+		log.Printf("Failed to audit impersonated request %s: %v", detail, err)
+	}
+}
 
+// apiKeyProtected authenticates an integration by API key. The request acts as
+// the key owner but every policy check is limited to the key scopes.
+func apiKeyProtected(ctx *fiber.Ctx, db *gorm.DB, apiKeys models.APIKeyServices, userPermissions models.UserPermissionRepository, rawKey string) error {
+	key, err := apiKeys.Authenticate(ctx.UserContext(), rawKey, ctx.IP())
+	if err != nil {
+		return errorMiddleware(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+
+	var user models.User
+	if err := db.First(&user, "id = ?", key.UserID).Error; err != nil {
+		return errorMiddleware(ctx, fiber.StatusUnauthorized, "User not found")
+	}
+
+	if user.StatusAt(time.Now()) == models.UserStatusSuspended {
+		return errorMiddleware(ctx, fiber.StatusForbidden, models.ErrAccountSuspended.Error())
+	}
+
+	ctx.Locals("userId", user.ID)
+	ctx.Locals("roleId", user.RoleID)
+	ctx.Locals("apiKeyId", key.ID)
+	ctx.Locals("branch", user.Branch)
+	ctx.SetUserContext(models.WithAPIKeyScopes(ctx.UserContext(), key.Scopes))
+	ctx.SetUserContext(models.WithAuditActor(ctx.UserContext(), models.AuditActor{UserID: user.ID, IP: ctx.IP()}))
+
+	if err := loadPermissionOverrides(ctx, userPermissions, user.ID, time.Now()); err != nil {
+		return errorMiddleware(ctx, fiber.StatusInternalServerError, "Failed to load user permissions")
+	}
+
+	return ctx.Next()
+}
+
+// loadPermissionOverrides attaches the user's active grants and denials to the
+// request context so every policy check of the request sees them, the cached
+// repository serves them without a query
+func loadPermissionOverrides(ctx *fiber.Ctx, userPermissions models.UserPermissionRepository, userId uuid.UUID, now time.Time) error {
+	permissions, err := userPermissions.GetActiveUserPermissions(ctx.UserContext(), userId, now)
+	if err != nil {
+		return err
+	}
+
+	ctx.SetUserContext(models.WithPermissionOverrides(ctx.UserContext(), models.OverridesOf(permissions, now)))
+	return nil
+}
+
+// BlockImpersonation rejects requests made with an impersonation token. Guard
+// every action support staff must never take on behalf of a customer with
+// it, such as changing the password, managing credentials or paying.
+func BlockImpersonation() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if _, ok := ctx.Locals("impersonatorId").(uuid.UUID); ok {
+			return errorMiddleware(ctx, fiber.StatusForbidden, "This action is not allowed while impersonating")
+		}
+		return ctx.Next()
+	}
+}
+
+// RequireSession rejects API key requests on routes that manage the account itself
+func RequireSession() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if _, ok := ctx.Locals("sessionId").(uuid.UUID); !ok {
+			return errorMiddleware(ctx, fiber.StatusForbidden, "This action requires an interactive login")
+		}
 		return ctx.Next()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,184 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/DestaAri1/RentAuto/policy"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RouteRule is one registered route and the permission it requires, empty
+// when the route is not guarded by a permission
+type RouteRule struct {
+	Method     string
+	Path       string
+	Permission string
+}
+
+// Authorizer guards routes with a permission and remembers which permission
+// every route was registered with, for the startup route report
+type Authorizer struct {
+	policy *policy.Policy
+
+	// register serialises the registrations so the OnRoute hook attributes
+	// each route to the permission of the call that adds it
+	register sync.Mutex
+
+	mu         sync.Mutex
+	permission string
+	rules      map[string]string
+}
+
+func NewAuthorizer(app *fiber.App, policy *policy.Policy) *Authorizer {
+	authorizer := &Authorizer{
+		policy: policy,
+		rules:  map[string]string{},
+	}
+
+	app.Hooks().OnRoute(authorizer.record)
+
+	return authorizer
+}
+
+// Get registers a GET route, and its HEAD route, that requires permission,
+// for example authorizer.Get(router, "/", policy.PermCarView, handler.GetCars).
+// Unknown permissions panic at startup.
+func (a *Authorizer) Get(router fiber.Router, path string, permission string, handlers ...fiber.Handler) {
+	a.add(router.Get, path, permission, handlers)
+}
+
+func (a *Authorizer) Post(router fiber.Router, path string, permission string, handlers ...fiber.Handler) {
+	a.add(router.Post, path, permission, handlers)
+}
+
+func (a *Authorizer) Put(router fiber.Router, path string, permission string, handlers ...fiber.Handler) {
+	a.add(router.Put, path, permission, handlers)
+}
+
+func (a *Authorizer) Patch(router fiber.Router, path string, permission string, handlers ...fiber.Handler) {
+	a.add(router.Patch, path, permission, handlers)
+}
+
+func (a *Authorizer) Delete(router fiber.Router, path string, permission string, handlers ...fiber.Handler) {
+	a.add(router.Delete, path, permission, handlers)
+}
+
+// add puts the permission check in front of the handlers and registers them.
+// Fiber runs the OnRoute hooks while the route is added, so record sees the
+// permission of this call only.
+func (a *Authorizer) add(register func(string, ...fiber.Handler) fiber.Router, path string, permission string, handlers []fiber.Handler) {
+	guard := a.requirePermission(permission)
+	permission, _ = policy.CanonicalPermission(permission)
+
+	a.register.Lock()
+	defer a.register.Unlock()
+
+	a.setPermission(permission)
+	defer a.setPermission("")
+
+	register(path, append([]fiber.Handler{guard}, handlers...)...)
+}
+
+func (a *Authorizer) setPermission(permission string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.permission = permission
+}
+
+// requirePermission rejects requests whose role, or API key, lacks the
+// permission. A scoped grant such as car.update.own also passes, handlers of
+// scoped permissions must call Policy.Authorize on the loaded resource.
+func (a *Authorizer) requirePermission(permission string) fiber.Handler {
+	if !policy.IsKnownPermission(permission) {
+		panic(fmt.Sprintf("Authorizer: unknown permission %q", permission))
+	}
+	permission, _ = policy.CanonicalPermission(permission)
+
+	return func(ctx *fiber.Ctx) error {
+		roleId, ok := ctx.Locals("roleId").(uuid.UUID)
+		if !ok {
+			return errorMiddleware(ctx, fiber.StatusUnauthorized, "Role ID not found in context")
+		}
+
+		err := a.policy.CheckAnyScope(ctx.UserContext(), roleId, permission)
+		switch {
+		case err == nil:
+			return ctx.Next()
+		case errors.Is(err, policy.ErrUnauthorized), errors.Is(err, policy.ErrRoleNotFound):
+			return errorMiddleware(ctx, fiber.StatusForbidden, fmt.Sprintf("You don't have the %q permission", permission))
+		default:
+			return errorMiddleware(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// record runs for every route added to the app, routes added outside of the
+// Authorizer require no permission
+func (a *Authorizer) record(route fiber.Route) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.permission == "" {
+		return nil
+	}
+
+	a.rules[route.Method+" "+route.Path] = a.permission
+	return nil
+}
+
+// Routes lists every route of the app with the permission it requires,
+// sorted by path. HEAD routes mirror GET and are left out.
+func (a *Authorizer) Routes(app *fiber.App) []RouteRule {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	seen := map[string]bool{}
+	var routes []RouteRule
+	for _, route := range app.GetRoutes(true) {
+		key := route.Method + " " + route.Path
+		if route.Method == fiber.MethodHead || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		routes = append(routes, RouteRule{
+			Method:     route.Method,
+			Path:       route.Path,
+			Permission: a.rules[key],
+		})
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes
+}
+
+// LogRoutes prints the route report, routes without a permission are marked
+// so unguarded admin endpoints stand out
+func (a *Authorizer) LogRoutes(app *fiber.App) {
+	routes := a.Routes(app)
+	guarded := 0
+
+	log.Printf("Registered routes:")
+	for _, route := range routes {
+		permission := route.Permission
+		if permission == "" {
+			permission = "- (no permission required)"
+		} else {
+			guarded++
+		}
+		log.Printf("  %-7s %-55s %s", route.Method, route.Path, permission)
+	}
+	log.Printf("%d routes, %d guarded by a permission", len(routes), guarded)
+}
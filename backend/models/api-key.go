@@ -0,0 +1,91 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKeyScheme is the Authorization scheme used to send an API key
+const APIKeyScheme = "ApiKey"
+
+// APIKey authenticates scripts and integrations as its owner, limited to Scopes.
+// Only a hash of the secret is stored, the full key is shown once on creation.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	User       User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16);not null;uniqueIndex"`
+	Hash       string     `json:"-" gorm:"type:char(64);not null"`
+	Scopes     []string   `json:"scopes" gorm:"type:json;serializer:json"`
+	CreatedBy  uuid.UUID  `json:"created_by" gorm:"type:char(36)"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip" gorm:"type:varchar(64)"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type APIKeyForm struct {
+	Name          string   `json:"name" validate:"required"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+type ServiceAccountForm struct {
+	Name string    `json:"name" validate:"required"`
+	Role uuid.UUID `json:"role" validate:"required"`
+}
+
+// CreatedAPIKey carries the plaintext key, it is never retrievable again
+type CreatedAPIKey struct {
+	APIKey *APIKey `json:"api_key"`
+	Key    string  `json:"key"`
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	GetAPIKeys(ctx context.Context, userId uuid.UUID) ([]*APIKey, error)
+	RevokeAPIKey(ctx context.Context, userId uuid.UUID, keyId uuid.UUID, now time.Time) (int64, error)
+	RevokeUserAPIKeys(ctx context.Context, userId uuid.UUID, now time.Time) error
+	TouchAPIKey(ctx context.Context, keyId uuid.UUID, now time.Time, ip string) error
+	CreateServiceAccount(ctx context.Context, user *User) error
+	GetServiceAccounts(ctx context.Context) ([]*User, error)
+	GetServiceAccount(ctx context.Context, userId uuid.UUID) (*User, error)
+	DeleteServiceAccount(ctx context.Context, userId uuid.UUID) error
+}
+
+type APIKeyServices interface {
+	CreateKey(ctx context.Context, ownerId uuid.UUID, createdBy uuid.UUID, formData *APIKeyForm) (*CreatedAPIKey, error)
+	ListKeys(ctx context.Context, ownerId uuid.UUID) ([]*APIKey, error)
+	RevokeKey(ctx context.Context, ownerId uuid.UUID, keyId uuid.UUID) error
+	Authenticate(ctx context.Context, rawKey string, ip string) (*APIKey, error)
+	CreateServiceAccount(ctx context.Context, formData *ServiceAccountForm) (*User, error)
+	ListServiceAccounts(ctx context.Context) ([]*User, error)
+	GetServiceAccount(ctx context.Context, userId uuid.UUID) (*User, error)
+	DeleteServiceAccount(ctx context.Context, userId uuid.UUID) error
+}
+
+type apiKeyScopesKey struct{}
+
+// WithAPIKeyScopes marks a request context as authenticated by an API key
+func WithAPIKeyScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, apiKeyScopesKey{}, scopes)
+}
+
+// APIKeyScopes returns the scopes of the API key behind the request, ok is
+// false for requests authenticated with a session token
+func APIKeyScopes(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(apiKeyScopesKey{}).([]string)
+	return scopes, ok
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	k.ID = uuid.New()
+	return
+}
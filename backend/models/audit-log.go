@@ -0,0 +1,179 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+	AuditStatus = "status"
+	// AuditRestore undoes a soft delete, AuditPurge anonymises a deleted record
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+	// AuditImpersonate marks the start of an impersonation, AuditRequest a
+	// request made while impersonating
+	AuditImpersonate = "impersonate"
+	AuditRequest     = "request"
+)
+
+const (
+	AuditEntityCar            = "car"
+	AuditEntityCarChild       = "car_child"
+	AuditEntityCarType        = "car_type"
+	AuditEntityRole           = "role"
+	AuditEntityUser           = "user"
+	AuditEntityUserPermission = "user_permission"
+	AuditEntitySetting        = "setting"
+	AuditEntityBlacklist      = "blacklist"
+	AuditEntityProfile        = "customer_profile"
+	AuditEntityErasure        = "erasure_request"
+)
+
+// auditRedacted replaces values that must never be written to the audit log
+const auditRedacted = "[redacted]"
+
+var ErrAuditLogImmutable = errors.New("audit log entries cannot be changed")
+
+// AuditLog records one administrative change. Entries are only ever inserted,
+// the hooks below refuse updates and deletes.
+type AuditLog struct {
+	ID      uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	ActorID *uuid.UUID `json:"actor_id" gorm:"type:char(36);index"`
+	// ImpersonatorID is the support user behind the actor during impersonation
+	ImpersonatorID *uuid.UUID             `json:"impersonator_id" gorm:"type:char(36);index"`
+	IP             string                 `json:"ip" gorm:"type:varchar(45)"`
+	Action         string                 `json:"action" gorm:"type:varchar(16);not null;index"`
+	Entity         string                 `json:"entity" gorm:"type:varchar(32);not null;index:idx_audit_entity"`
+	EntityID       uuid.UUID              `json:"entity_id" gorm:"type:char(36);not null;index:idx_audit_entity"`
+	Changes        map[string]AuditChange `json:"changes" gorm:"type:json;serializer:json"`
+	// Detail describes entries without field changes, such as requests
+	Detail    string    `json:"detail,omitempty" gorm:"type:varchar(255)"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// AuditChange is the value of one field before and after the change, nil
+// when the field did not exist yet or no longer exists
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditLogFilter narrows the audit log listing, zero values match everything
+type AuditLogFilter struct {
+	ActorID        uuid.UUID
+	ImpersonatorID uuid.UUID
+	Entity         string
+	EntityID       uuid.UUID
+	Action         string
+	From           *time.Time
+	To             *time.Time
+}
+
+// AuditActor is the user a change is made by and the address it came from.
+// ImpersonatorID is set when support staff act as the user.
+type AuditActor struct {
+	UserID         uuid.UUID
+	ImpersonatorID uuid.UUID
+	IP             string
+}
+
+type AuditLogRepository interface {
+	GetAuditLogs(ctx context.Context, filter *AuditLogFilter, page *PageQuery) (*Page, error)
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor attaches the requesting user to the context, repositories
+// read it back when they record their changes
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActorFrom returns the actor attached to the context, ok is false for
+// changes made outside a request such as seeding
+func AuditActorFrom(ctx context.Context) (AuditActor, bool) {
+	actor, ok := ctx.Value(auditActorKey{}).(AuditActor)
+	return actor, ok
+}
+
+// DiffOf compares two records by their JSON form and returns the fields that
+// differ. Pass nil as before for a create and as after for a delete. Fields
+// hidden from JSON, such as passwords, never show up, nested associations and
+// timestamps are left out.
+func DiffOf(before interface{}, after interface{}) (map[string]AuditChange, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]AuditChange{}
+	for field, value := range beforeFields {
+		if other, ok := afterFields[field]; !ok || !reflect.DeepEqual(value, other) {
+			changes[field] = AuditChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = AuditChange{After: value}
+		}
+	}
+
+	return changes, nil
+}
+
+// Redact marks a field as changed without revealing its values
+func Redact(changes map[string]AuditChange, field string) {
+	changes[field] = AuditChange{Before: auditRedacted, After: auditRedacted}
+}
+
+func auditFields(record interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if record == nil || reflect.ValueOf(record).IsZero() {
+		return fields, nil
+	}
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	for field, value := range fields {
+		if _, nested := value.(map[string]interface{}); nested {
+			delete(fields, field)
+		}
+	}
+	delete(fields, "created_at")
+	delete(fields, "updated_at")
+	delete(fields, "deleted_at")
+
+	return fields, nil
+}
+
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) (err error) {
+	a.ID = uuid.New()
+	return
+}
+
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) (err error) {
+	return ErrAuditLogImmutable
+}
+
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) (err error) {
+	return ErrAuditLogImmutable
+}
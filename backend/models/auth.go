@@ -2,22 +2,32 @@ package models
 
 import (
 	"context"
+	"errors"
 	"net/mail"
+	"time"
 
+	"github.com/DestaAri1/RentAuto/utils"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthCredentials struct {
 	Username string `json:"username" validate:"required"`
 	Email    string `json:"email" validate:"required,email,omitempty"`
 	Password string `json:"password" validate:"required"`
+	// Phone and IDNumber are optional, they are checked against the blacklist.
+	// They are not stored, the profile is checked again once it is saved.
+	Phone    string `json:"phone" validate:"omitempty,max=32"`
+	IDNumber string `json:"id_number" validate:"omitempty,max=64"`
 }
 
 type AuthRepository interface {
 	RegisterUser(ctx context.Context, registerData *AuthCredentials) (*User, error)
 	GetUser(ctx context.Context, query interface{}, args ...interface{}) (*User, error)
 	GetUserWithRole(ctx context.Context, userId uuid.UUID, user *User) error
+	// FindOrCreateOIDCUser calls canLink before an existing account is linked
+	// by its email
+	FindOrCreateOIDCUser(ctx context.Context, provider string, claims *OIDCClaims, canLink func(user *User) error) (*User, error)
+	LinkOIDCIdentity(ctx context.Context, userId uuid.UUID, provider string, claims *OIDCClaims) error
 }
 
 type LoginCredentials struct {
@@ -26,16 +36,40 @@ type LoginCredentials struct {
 }
 
 type AuthServices interface {
-	Login(ctx context.Context, loginData *LoginCredentials) (string, *User, error)
-	Register(ctx context.Context, registerData *AuthCredentials) (string, *User, error)
+	Login(ctx context.Context, loginData *LoginCredentials, client ClientInfo) (*LoginResult, error)
+	Register(ctx context.Context, registerData *AuthCredentials, client ClientInfo) (string, *User, error)
+	VerifyMFALogin(ctx context.Context, formData *MFAChallengeForm, client ClientInfo) (string, *User, error)
+	StartMFASetup(ctx context.Context, formData *MFASetupForm) (*MFAEnrollment, error)
+	CompleteMFASetup(ctx context.Context, formData *MFAChallengeForm, client ClientInfo) (string, *User, []string, error)
+	EnrollMFA(ctx context.Context, userId uuid.UUID) (*MFAEnrollment, error)
+	ConfirmMFA(ctx context.Context, userId uuid.UUID, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, userId uuid.UUID, code string) ([]string, error)
+	DisableMFA(ctx context.Context, userId uuid.UUID, code string) error
+	ChangePassword(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID, formData *ChangePasswordForm) error
+	OIDCProviders() []string
+	// OIDCAuthorize links the identity to linkUserId instead of logging in
+	// when linkUserId is set
+	OIDCAuthorize(ctx context.Context, provider string, linkUserId uuid.UUID) (*OIDCAuthorization, error)
+	OIDCCallback(ctx context.Context, provider string, code string, state string, stateToken string, client ClientInfo) (*LoginResult, error)
+	Impersonate(ctx context.Context, impersonatorId uuid.UUID, userId uuid.UUID, client ClientInfo) (*ImpersonationResult, error)
 }
 
-//Check if password matches a has
+var ErrCannotImpersonate = errors.New("this account cannot be impersonated")
+
+// ImpersonationResult is the token support staff use to act as a customer
+type ImpersonationResult struct {
+	Token          string    `json:"token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	ImpersonatorID uuid.UUID `json:"impersonator_id"`
+	User           *User     `json:"user"`
+}
+
+//Check if password matches a hash, either argon2id or legacy bcrypt
 
 func MatchesHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	ok, _ := utils.VerifyPassword(password, hash)
 
-	return err == nil
+	return ok
 }
 
 //Check if an email is valid
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrBlacklisted does not say which identifier matched, that stays with the admins
+var ErrBlacklisted = errors.New("registration is not possible, please contact support")
+
+// ErrProfileBlacklisted is returned when a saved profile adds a phone number or
+// licence number that is on the blacklist
+var ErrProfileBlacklisted = errors.New("these details cannot be used, please contact support")
+
+// BlacklistEntry keeps a customer from registering again. Any of Email, Phone
+// and IDNumber matches, they are stored normalised. UserID is set when the
+// entry was created by blacklisting an existing account.
+type BlacklistEntry struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    *uuid.UUID `json:"user_id" gorm:"type:char(36);index"`
+	Email     string     `json:"email" gorm:"type:varchar(255);index"`
+	Phone     string     `json:"phone" gorm:"type:varchar(32);index"`
+	IDNumber  string     `json:"id_number" gorm:"type:varchar(64);index"`
+	Reason    string     `json:"reason" gorm:"type:varchar(255);not null"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedBy uuid.UUID  `json:"created_by" gorm:"type:char(36)"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type BlacklistForm struct {
+	Email     string     `json:"email" validate:"omitempty,email"`
+	Phone     string     `json:"phone" validate:"omitempty,max=32"`
+	IDNumber  string     `json:"id_number" validate:"omitempty,max=64"`
+	Reason    string     `json:"reason" validate:"required,max=255"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type BlacklistRepository interface {
+	GetBlacklist(ctx context.Context, page *PageQuery) (*Page, error)
+	CreateBlacklistEntry(ctx context.Context, entry *BlacklistEntry) error
+	DeleteBlacklistEntry(ctx context.Context, entryId uuid.UUID) (int64, error)
+	// IsBlacklisted reports whether any of the identifiers is on the active
+	// blacklist, empty identifiers are ignored
+	IsBlacklisted(ctx context.Context, email string, phone string, idNumber string) (bool, error)
+}
+
+// NormalizeEmail, NormalizePhone and NormalizeIDNumber bring identifiers to
+// the form they are stored and compared in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizePhone keeps the digits and a leading plus
+func NormalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+
+	var b strings.Builder
+	for i, r := range phone {
+		if unicode.IsDigit(r) || r == '+' && i == 0 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// NormalizeIDNumber drops separators and ignores case
+func NormalizeIDNumber(idNumber string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(idNumber) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (e *BlacklistEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	e.ID = uuid.New()
+	return
+}
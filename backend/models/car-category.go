@@ -36,6 +36,7 @@ type CarTypeResponses struct {
 
 type CarTypesRepository interface {
 	GetCarType(ctx context.Context) ([]*CarTypeResponses, error)
+	GetCarTypeByID(ctx context.Context, carTypeId uuid.UUID) (*CarTypes, error)
 	CreateCarType(ctx context.Context, formData *FormCarTypes, userId uuid.UUID) error
 	UpdateCarType(ctx context.Context, updateData map[string]interface{}, carTypeId uuid.UUID, userId uuid.UUID) error
 	DeleteCarType(ctx context.Context, carTypeId uuid.UUID) error
@@ -44,4 +45,14 @@ type CarTypesRepository interface {
 func (r *CarTypes) BeforeCreate(tx *gorm.DB) (err error) {
 	r.ID = uuid.New()
 	return
+}
+
+// OwnerID and BranchName let policies evaluate scoped permissions, car types
+// are shared by every branch
+func (t *CarTypes) OwnerID() uuid.UUID {
+	return t.UserId
+}
+
+func (t *CarTypes) BranchName() string {
+	return ""
 }
\ No newline at end of file
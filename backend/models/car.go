@@ -21,6 +21,7 @@ type CarParent struct {
 	Rating    int            `json:"rating" gorm:"default:0"`
 	UserId    uuid.UUID      `json:"user_id" gorm:"not null"`
 	User      User           `json:"user" gorm:"foreignKey:UserId;references:ID;onDelete:cascade"`
+	Branch    string         `json:"branch" gorm:"type:varchar(64);index"`
 	TimeStruct
 }
 
@@ -111,6 +112,7 @@ type StatusForm struct {
 
 type CarRepository interface {
 	GetCars(ctx context.Context) ([]*CarParentResponse, error)
+	GetCar(ctx context.Context, carId uuid.UUID) (*CarParent, error)
 	CreateCar(ctx context.Context, formData *FormCarParent, userId uuid.UUID) error
 	UpdateCar(ctx context.Context, updateData map[string]interface{}, carId uuid.UUID, userId uuid.UUID) error
 	DeleteCar(ctx context.Context, carId uuid.UUID) error
@@ -119,12 +121,23 @@ type CarRepository interface {
 type CarChildRepository interface {
 	GetCarChilds(ctx context.Context, carParentSlug string) ([]*CarChildResponse, error)
 	GetOneCarChild(ctx context.Context, carChildSlug string) (*CarChildResponse, error)
+	GetCarParent(ctx context.Context, carParentId uuid.UUID) (*CarParent, error)
+	GetCarChildParent(ctx context.Context, carChildId uuid.UUID) (*CarParent, error)
 	CreateCarChild(ctx context.Context, formData *FormCarChild, userId uuid.UUID) error
 	UpdateCarChild(ctx context.Context, updateData map[string]interface{}, userId uuid.UUID, carChildId uuid.UUID) error
 	UpdateStatusCarChild(ctx context.Context, updateData map[string]interface{}, userId uuid.UUID, carChildId uuid.UUID) error
 	DeleteCarChild(ctx context.Context, carChildId uuid.UUID) error
 }
 
+// OwnerID and BranchName let policies evaluate scoped permissions on a car
+func (r *CarParent) OwnerID() uuid.UUID {
+	return r.UserId
+}
+
+func (r *CarParent) BranchName() string {
+	return r.Branch
+}
+
 func (r *CarParent) BeforeCreate(tx *gorm.DB) (err error) {
 	r.ID.ID = uuid.New()
 	return
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DateLayout is the format of the date only fields of the profile
+const DateLayout = "2006-01-02"
+
+// CustomerProfile holds the contact and identity data needed to rent a car.
+// Every field is optional until the customer books, see EligibilityServices.
+type CustomerProfile struct {
+	UserID                   uuid.UUID  `json:"user_id" gorm:"type:char(36);primaryKey"`
+	User                     User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Phone                    string     `json:"phone" gorm:"type:varchar(32)"`
+	Address                  string     `json:"address" gorm:"type:varchar(255)"`
+	DateOfBirth              *time.Time `json:"date_of_birth" gorm:"type:date"`
+	LicenceNumber            string     `json:"licence_number" gorm:"type:varchar(64);index"`
+	LicenceClass             string     `json:"licence_class" gorm:"type:varchar(8)"`
+	LicenceExpiresAt         *time.Time `json:"licence_expires_at" gorm:"type:date"`
+	EmergencyContactName     string     `json:"emergency_contact_name" gorm:"type:varchar(128)"`
+	EmergencyContactPhone    string     `json:"emergency_contact_phone" gorm:"type:varchar(32)"`
+	EmergencyContactRelation string     `json:"emergency_contact_relation" gorm:"type:varchar(64)"`
+	CreatedAt                time.Time  `json:"created_at"`
+	UpdatedAt                time.Time  `json:"updated_at"`
+}
+
+// CustomerProfileForm replaces the whole profile, dates use DateLayout
+type CustomerProfileForm struct {
+	Phone                    string `json:"phone" validate:"omitempty,max=32"`
+	Address                  string `json:"address" validate:"omitempty,max=255"`
+	DateOfBirth              string `json:"date_of_birth" validate:"omitempty,datetime=2006-01-02"`
+	LicenceNumber            string `json:"licence_number" validate:"omitempty,max=64"`
+	LicenceClass             string `json:"licence_class" validate:"omitempty,max=8"`
+	LicenceExpiresAt         string `json:"licence_expires_at" validate:"omitempty,datetime=2006-01-02"`
+	EmergencyContactName     string `json:"emergency_contact_name" validate:"omitempty,max=128"`
+	EmergencyContactPhone    string `json:"emergency_contact_phone" validate:"omitempty,max=32"`
+	EmergencyContactRelation string `json:"emergency_contact_relation" validate:"omitempty,max=64"`
+}
+
+// Profile checks what the validator tags cannot and builds the profile. The
+// returned problems are keyed by field like the validation errors.
+func (f *CustomerProfileForm) Profile(userId uuid.UUID, now time.Time) (*CustomerProfile, map[string]string) {
+	problems := map[string]string{}
+
+	profile := &CustomerProfile{
+		UserID:                   userId,
+		Phone:                    NormalizePhone(f.Phone),
+		Address:                  strings.TrimSpace(f.Address),
+		LicenceNumber:            NormalizeIDNumber(f.LicenceNumber),
+		LicenceClass:             strings.ToUpper(strings.TrimSpace(f.LicenceClass)),
+		EmergencyContactName:     strings.TrimSpace(f.EmergencyContactName),
+		EmergencyContactPhone:    NormalizePhone(f.EmergencyContactPhone),
+		EmergencyContactRelation: strings.TrimSpace(f.EmergencyContactRelation),
+	}
+
+	if f.Phone != "" && !isPhoneNumber(profile.Phone) {
+		problems["Phone"] = "Phone must have 8 to 15 digits"
+	}
+	if f.EmergencyContactPhone != "" && !isPhoneNumber(profile.EmergencyContactPhone) {
+		problems["EmergencyContactPhone"] = "Emergency contact phone must have 8 to 15 digits"
+	}
+	if (profile.EmergencyContactName == "") != (profile.EmergencyContactPhone == "") {
+		problems["EmergencyContactName"] = "Emergency contact needs both a name and a phone"
+	}
+
+	if f.DateOfBirth != "" {
+		// The datetime tag already checked the layout
+		dateOfBirth, _ := time.Parse(DateLayout, f.DateOfBirth)
+		if !dateOfBirth.Before(now) || dateOfBirth.Year() < 1900 {
+			problems["DateOfBirth"] = "Date of birth must be in the past"
+		}
+		profile.DateOfBirth = &dateOfBirth
+	}
+
+	if f.LicenceExpiresAt != "" {
+		licenceExpiresAt, _ := time.Parse(DateLayout, f.LicenceExpiresAt)
+		profile.LicenceExpiresAt = &licenceExpiresAt
+	}
+	hasLicence := profile.LicenceNumber != ""
+	if hasLicence != (profile.LicenceClass != "") || hasLicence != (profile.LicenceExpiresAt != nil) {
+		problems["LicenceNumber"] = "Licence needs a number, class and expiry date"
+	}
+
+	return profile, problems
+}
+
+// AgeAt is the age in whole years on day, zero without a date of birth
+func (p *CustomerProfile) AgeAt(day time.Time) int {
+	if p.DateOfBirth == nil {
+		return 0
+	}
+
+	dateOfBirth := *p.DateOfBirth
+	age := day.Year() - dateOfBirth.Year()
+	if day.Month() < dateOfBirth.Month() || day.Month() == dateOfBirth.Month() && day.Day() < dateOfBirth.Day() {
+		age--
+	}
+	return age
+}
+
+func isPhoneNumber(phone string) bool {
+	digits := len(strings.TrimPrefix(phone, "+"))
+	return digits >= 8 && digits <= 15
+}
+
+// Eligibility explains whether a customer may book, Reasons is empty when
+// Eligible is true
+type Eligibility struct {
+	Eligible bool     `json:"eligible"`
+	Reasons  []string `json:"reasons"`
+}
+
+type CustomerProfileRepository interface {
+	// GetProfile returns an empty profile for users who never saved one
+	GetProfile(ctx context.Context, userId uuid.UUID) (*CustomerProfile, error)
+	// SaveProfile returns ErrProfileBlacklisted when a new phone or licence
+	// number is on the blacklist
+	SaveProfile(ctx context.Context, profile *CustomerProfile) error
+}
+
+type EligibilityServices interface {
+	// CheckEligibility decides whether userId may rent a car from start until
+	// end, booking must call it before an order is created
+	CheckEligibility(ctx context.Context, userId uuid.UUID, start time.Time, end time.Time) (*Eligibility, error)
+}
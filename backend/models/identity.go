@@ -0,0 +1,51 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const OIDCPurposeState = "oidc_state"
+
+var (
+	// ErrIdentityLinkRefused is returned when a login would link an identity to
+	// a protected, administrative or service account by its email. Such
+	// accounts link identities from their account settings.
+	ErrIdentityLinkRefused = errors.New("sign in with your password and link the identity provider from your account settings")
+	ErrIdentityLinked      = errors.New("this identity is already linked to another account")
+)
+
+// UserIdentity links a user to an account at an external OpenID Connect provider
+type UserIdentity struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"`
+	User      User      `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Provider  string    `json:"provider" gorm:"type:varchar(64);not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject   string    `json:"subject" gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_provider_subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OIDCClaims are the ID token claims used to link or create a user
+type OIDCClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCAuthorization is the provider redirect plus the signed state the client
+// must send back with the callback
+type OIDCAuthorization struct {
+	URL        string `json:"url"`
+	StateToken string `json:"-"`
+}
+
+func (i *UserIdentity) BeforeCreate(tx *gorm.DB) (err error) {
+	i.ID = uuid.New()
+	return
+}
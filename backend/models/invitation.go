@@ -0,0 +1,84 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidInvitation      = errors.New("invitation is invalid or has expired")
+	ErrInvitationNotPending   = errors.New("pending invitation not found")
+	ErrInvitationUserNotFound = errors.New("user not found")
+	ErrNoPasswordToReset      = errors.New("service accounts have no password to reset")
+)
+
+// UserInvitation lets a user created by an admin choose their own password.
+// Only a hash of the token is stored, the token itself is only in the email.
+// A user has at most one open invitation, a new one revokes the previous.
+// Reset marks invitations sent to existing users to replace their password.
+type UserInvitation struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	User       User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	TokenHash  string     `json:"-" gorm:"type:char(64);not null;uniqueIndex"`
+	CreatedBy  uuid.UUID  `json:"created_by" gorm:"type:char(36)"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	Reset      bool       `json:"reset" gorm:"default:false"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// PendingInvitation is an invitation that was neither accepted nor revoked,
+// expired ones are listed too so they can be resent
+type PendingInvitation struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedBy uuid.UUID `json:"created_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Expired   bool      `json:"expired"`
+	Reset     bool      `json:"reset"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AcceptInvitationForm struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type InvitationRepository interface {
+	// CreateInvitation revokes the open invitations of the user first
+	CreateInvitation(ctx context.Context, invitation *UserInvitation) error
+	GetInvitationByHash(ctx context.Context, hash string) (*UserInvitation, error)
+	GetPendingInvitations(ctx context.Context, page *PageQuery) (*Page, error)
+	GetPendingInvitation(ctx context.Context, invitationId uuid.UUID) (*UserInvitation, error)
+	RevokeInvitation(ctx context.Context, invitationId uuid.UUID, now time.Time) error
+	// AcceptInvitation marks the invitation used and the user's email verified,
+	// it returns false when the invitation was already used
+	AcceptInvitation(ctx context.Context, invitationId uuid.UUID, now time.Time) (bool, error)
+}
+
+type InvitationServices interface {
+	// Invite emails user a link to set their password
+	Invite(ctx context.Context, user *User, invitedBy uuid.UUID) error
+	// SendPasswordReset emails an existing user a link to choose a new
+	// password, the current password keeps working until the link is used
+	SendPasswordReset(ctx context.Context, userId uuid.UUID, requestedBy uuid.UUID) error
+	Accept(ctx context.Context, formData *AcceptInvitationForm) error
+	GetPendingInvitations(ctx context.Context, page *PageQuery) (*Page, error)
+	// Resend replaces the invitation with a new link and expiry, the old link
+	// stops working
+	Resend(ctx context.Context, invitationId uuid.UUID, invitedBy uuid.UUID) error
+	Revoke(ctx context.Context, invitationId uuid.UUID) error
+}
+
+func (i *UserInvitation) BeforeCreate(tx *gorm.DB) (err error) {
+	i.ID = uuid.New()
+	return
+}
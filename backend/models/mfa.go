@@ -0,0 +1,102 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	MFAPurposeLogin = "mfa_login"
+	MFAPurposeSetup = "mfa_setup"
+	// MFAPurposeAccount challenges are never sent to the client, they count
+	// the codes entered in the account settings
+	MFAPurposeAccount = "mfa_account"
+)
+
+type UserMFA struct {
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:char(36);primaryKey"`
+	User          User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Secret        string     `json:"-" gorm:"not null"` // encrypted at rest by the repository
+	Enabled       bool       `json:"enabled" gorm:"default:false"`
+	RecoveryCodes []string   `json:"-" gorm:"type:json;serializer:json"`
+	LastUsedStep  int64      `json:"-" gorm:"default:0"`
+	ConfirmedAt   *time.Time `json:"confirmed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+var ErrTooManyMFAAttempts = errors.New("too many invalid verification codes, try again later")
+
+// MFAChallenge backs the jti of a challenge token so each token allows a
+// limited number of codes and can be used only once
+type MFAChallenge struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	User      User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Purpose   string     `json:"purpose" gorm:"type:varchar(16);not null"`
+	Attempts  int        `json:"attempts" gorm:"not null;default:0"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+}
+
+func (c *MFAChallenge) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	return
+}
+
+type MFACodeForm struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFAChallengeForm struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type MFASetupForm struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// LoginResult is returned by the first login step. When MFA is required the
+// token is empty and the client must finish the login with ChallengeToken.
+type LoginResult struct {
+	Token            string `json:"token,omitempty"`
+	User             *User  `json:"user,omitempty"`
+	MFARequired      bool   `json:"mfa_required"`
+	MFASetupRequired bool   `json:"mfa_setup_required"`
+	ChallengeToken   string `json:"challenge_token,omitempty"`
+	// Linked is set when the callback linked an identity to a signed in user
+	Linked bool `json:"linked,omitempty"`
+}
+
+type MFARepository interface {
+	GetMFA(ctx context.Context, userId uuid.UUID) (*UserMFA, error)
+	SaveMFA(ctx context.Context, mfa *UserMFA) error
+	DeleteMFA(ctx context.Context, userId uuid.UUID) error
+	// SealPlaintextSecrets encrypts secrets stored in plaintext and returns
+	// how many there were
+	SealPlaintextSecrets(ctx context.Context) (int, error)
+
+	// CreateChallenge also drops the user's challenges that expired before
+	// the failure window
+	CreateChallenge(ctx context.Context, challenge *MFAChallenge, window time.Duration) error
+	GetChallenge(ctx context.Context, challengeId uuid.UUID) (*MFAChallenge, error)
+	// ReserveChallengeAttempt counts one code against the challenge before it
+	// is checked, false when the challenge is used, expired or out of attempts
+	ReserveChallengeAttempt(ctx context.Context, challengeId uuid.UUID, maxAttempts int, now time.Time) (bool, error)
+	// UseChallenge marks the challenge used, false when it already was
+	UseChallenge(ctx context.Context, challengeId uuid.UUID, now time.Time) (bool, error)
+	// CountChallengeFailures counts the invalid codes entered for the user's
+	// challenges created since
+	CountChallengeFailures(ctx context.Context, userId uuid.UUID, since time.Time) (int64, error)
+}
@@ -0,0 +1,38 @@
+package models
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageQuery is the requested page of a listing, pages start at 1
+type PageQuery struct {
+	Page  int
+	Limit int
+}
+
+// Page is one page of a listing together with the total number of items
+type Page struct {
+	Items interface{} `json:"items"`
+	Total int64       `json:"total"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+}
+
+// NewPageQuery clamps the requested page and limit to sane values
+func NewPageQuery(page int, limit int) *PageQuery {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	return &PageQuery{Page: page, Limit: limit}
+}
+
+func (q *PageQuery) Offset() int {
+	return (q.Page - 1) * q.Limit
+}
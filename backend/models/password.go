@@ -0,0 +1,45 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordHistory keeps previous password hashes to prevent reuse
+type PasswordHistory struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"`
+	User      User      `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Hash      string    `json:"-" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChangePasswordForm struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type PasswordRepository interface {
+	GetPasswordHistory(ctx context.Context, userId uuid.UUID, limit int) ([]*PasswordHistory, error)
+	AddPasswordHistory(ctx context.Context, userId uuid.UUID, hash string, keep int) error
+	UpdatePasswordHash(ctx context.Context, userId uuid.UUID, hash string) error
+}
+
+type PasswordServices interface {
+	// Check validates a new password against the policy. userId may be uuid.Nil
+	// for users that do not exist yet, related holds values like the email or
+	// name that must not appear in the password.
+	Check(ctx context.Context, userId uuid.UUID, password string, related ...string) error
+	Hash(password string) (string, error)
+	Remember(ctx context.Context, userId uuid.UUID, hash string) error
+	Verify(ctx context.Context, user *User, password string) bool
+	SetPassword(ctx context.Context, user *User, password string) error
+}
+
+func (p *PasswordHistory) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID = uuid.New()
+	return
+}
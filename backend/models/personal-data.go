@@ -0,0 +1,92 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Erasure request states, an approved request has already been carried out
+const (
+	ErasurePending   = "pending"
+	ErasureApproved  = "approved"
+	ErasureRejected  = "rejected"
+	ErasureCancelled = "cancelled"
+)
+
+var (
+	ErrErasurePending  = errors.New("an erasure request is already pending")
+	ErrErasureNotFound = errors.New("pending erasure request not found")
+)
+
+// ErasureRequest is a customer's request to have their account erased. Staff
+// approve it before the account is anonymised, for example once open orders
+// are settled. The request outlives the account as proof it was honoured.
+type ErasureRequest struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	Reason     string     `json:"reason" gorm:"type:varchar(500)"`
+	Status     string     `json:"status" gorm:"type:varchar(16);not null;default:pending;index"`
+	ReviewedBy *uuid.UUID `json:"reviewed_by" gorm:"type:char(36)"`
+	ReviewNote string     `json:"review_note" gorm:"type:varchar(500)"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (e *ErasureRequest) BeforeCreate(tx *gorm.DB) (err error) {
+	e.ID = uuid.New()
+	return
+}
+
+type ErasureRequestForm struct {
+	Reason string `json:"reason" validate:"omitempty,max=500"`
+}
+
+type ErasureReviewForm struct {
+	Note string `json:"note" validate:"omitempty,max=500"`
+}
+
+// PersonalDataOrder is an order as shown to its customer
+type PersonalDataOrder struct {
+	ID            uuid.UUID `json:"id"`
+	Car           string    `json:"car"`
+	Information   string    `json:"information"`
+	IsActive      bool      `json:"is_active"`
+	IsPickedUp    bool      `json:"is_picked_up"`
+	PaymentStatus bool      `json:"payment_status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PersonalData is everything stored about one user, secrets such as password
+// and key hashes are left out by their JSON tags
+type PersonalData struct {
+	ExportedAt      time.Time            `json:"exported_at"`
+	User            *User                `json:"user"`
+	Profile         *CustomerProfile     `json:"profile"`
+	Orders          []*PersonalDataOrder `json:"orders"`
+	Sessions        []*Session           `json:"sessions"`
+	Identities      []*UserIdentity      `json:"identities"`
+	APIKeys         []*APIKey            `json:"api_keys"`
+	Permissions     []*UserPermission    `json:"permissions"`
+	ErasureRequests []*ErasureRequest    `json:"erasure_requests"`
+	// AuditLogs are the recorded changes to the user's account and profile
+	AuditLogs []*AuditLog `json:"audit_logs"`
+}
+
+type PersonalDataRepository interface {
+	GetPersonalData(ctx context.Context, userId uuid.UUID) (*PersonalData, error)
+	// GetErasureRequest returns the latest request of the user, nil if none
+	GetErasureRequest(ctx context.Context, userId uuid.UUID) (*ErasureRequest, error)
+	CreateErasureRequest(ctx context.Context, request *ErasureRequest) error
+	CancelErasureRequest(ctx context.Context, userId uuid.UUID) error
+	// GetErasureRequests lists requests oldest first, status narrows the list
+	GetErasureRequests(ctx context.Context, status string, page *PageQuery) (*Page, error)
+	// ApproveErasureRequest deletes and anonymises the account in the same
+	// transaction, orders are kept for the accounts
+	ApproveErasureRequest(ctx context.Context, requestId uuid.UUID, reviewedBy uuid.UUID, note string) error
+	RejectErasureRequest(ctx context.Context, requestId uuid.UUID, reviewedBy uuid.UUID, note string) error
+}
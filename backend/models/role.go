@@ -12,6 +12,10 @@ type Role struct {
 	ID         uuid.UUID      `json:"id" gorm:"type:char(36);primaryKey"`
 	Name       string         `json:"name" gorm:"unique;not null"`
 	Permission []string       `json:"permission" gorm:"type:json;serializer:json"`
+	// Parents are the roles this role inherits permissions from
+	Parents    []uuid.UUID    `json:"parents" gorm:"type:json;serializer:json"`
+	// IsSystem marks the seeded roles, they cannot be renamed or deleted
+	IsSystem   bool           `json:"is_system" gorm:"default:false"`
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `json:"deleted_at"`
@@ -20,16 +24,27 @@ type Role struct {
 type FormRole struct {
 	Name	   string 	`json:"name" validate:"required"`
 	Permission []string `json:"permission" validate:"required,dive,required"`
+	Parents    []uuid.UUID `json:"parents" validate:"omitempty,dive,required"`
 }
 
 type RoleResponse struct {
 	ID         uuid.UUID      `json:"id"`
 	Name       string         `json:"name"`
 	Permission []string       `json:"permission"`
+	Parents    []uuid.UUID    `json:"parents"`
+	IsSystem   bool           `json:"is_system"`
+}
+
+// EffectivePermissions is a role's own permissions merged with everything it inherits
+type EffectivePermissions struct {
+	Role          *RoleResponse   `json:"role"`
+	Permissions   []string        `json:"permissions"`
+	InheritedFrom []*RoleResponse `json:"inherited_from"`
 }
 
 type RoleRepository interface {
 	GetRoles(ctx context.Context) ([]*RoleResponse, error)
+	GetRole(ctx context.Context, roleId uuid.UUID) (*RoleResponse, error)
 	CreateRole(ctx context.Context, formData *FormRole) error
 	UpdateRole(ctx context.Context, formData *FormRole, roleId uuid.UUID) error
 	DeleteRole(ctx context.Context, roleId uuid.UUID) error
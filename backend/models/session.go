@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session is a login on one device. Access tokens carry the session ID in the
+// sid claim and stop working as soon as the session is revoked or expires.
+// ImpersonatorID is set on sessions support staff opened as the user.
+type Session struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID         uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	User           User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Device         string     `json:"device" gorm:"type:varchar(128)"`
+	UserAgent      string     `json:"user_agent" gorm:"type:varchar(512)"`
+	IPAddress      string     `json:"ip_address" gorm:"type:varchar(64)"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"index"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty" gorm:"type:char(36);index"`
+	Current        bool       `json:"current" gorm:"-"`
+}
+
+// ClientInfo describes the device a login request came from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *Session) error
+	GetSession(ctx context.Context, sessionId uuid.UUID) (*Session, error)
+	GetActiveSessions(ctx context.Context, userId uuid.UUID, now time.Time) ([]*Session, error)
+	TouchSession(ctx context.Context, sessionId uuid.UUID, now time.Time) error
+	RevokeSession(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID, now time.Time) (int64, error)
+	RevokeUserSessions(ctx context.Context, userId uuid.UUID, exceptId uuid.UUID, now time.Time) (int64, error)
+	DeleteExpiredSessions(ctx context.Context, before time.Time) error
+}
+
+type SessionServices interface {
+	CreateSession(ctx context.Context, userId uuid.UUID, client ClientInfo, expiresAt time.Time) (*Session, error)
+	CreateImpersonationSession(ctx context.Context, userId uuid.UUID, impersonatorId uuid.UUID, client ClientInfo, expiresAt time.Time) (*Session, error)
+	ListSessions(ctx context.Context, userId uuid.UUID, currentId uuid.UUID) ([]*Session, error)
+	RevokeSession(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userId uuid.UUID, currentId uuid.UUID) (int64, error)
+	RevokeAllSessions(ctx context.Context, userId uuid.UUID) (int64, error)
+}
+
+// IsActive reports whether the session can still authenticate requests
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+func (s *Session) BeforeCreate(tx *gorm.DB) (err error) {
+	s.ID = uuid.New()
+	return
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Setting types, they decide how a stored value is parsed
+const (
+	SettingInt        = "int"
+	SettingFloat      = "float"
+	SettingBool       = "bool"
+	SettingString     = "string"
+	SettingDuration   = "duration"
+	SettingStringList = "string_list"
+)
+
+// Known settings, the schema in the settings service declares their type,
+// default and validation
+const (
+	SettingHoldDuration           = "rental.hold_duration"
+	SettingLateFeeRate            = "rental.late_fee_rate"
+	SettingTaxRate                = "rental.tax_rate"
+	SettingMinDriverAge           = "rental.min_driver_age"
+	SettingLicenceClasses         = "rental.licence_classes"
+	SettingUploadMaxFileSize      = "upload.max_file_size"
+	SettingUploadAllowedMimeTypes = "upload.allowed_mime_types"
+)
+
+var ErrUnknownSetting = errors.New("unknown setting")
+
+// Setting is a stored value, only settings changed by an admin are stored,
+// the others use their default. Value holds the JSON encoded value.
+type Setting struct {
+	Key       string     `json:"key" gorm:"type:varchar(64);primaryKey"`
+	Value     string     `json:"value" gorm:"type:text;not null"`
+	UpdatedBy *uuid.UUID `json:"updated_by" gorm:"type:char(36)"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// SettingDefinition declares a setting. Validate receives the parsed value,
+// an int64, float64, bool, string, time.Duration or []string by Type.
+type SettingDefinition struct {
+	Key         string                  `json:"key"`
+	Type        string                  `json:"type"`
+	Default     interface{}             `json:"default"`
+	Description string                  `json:"description"`
+	Validate    func(interface{}) error `json:"-"`
+}
+
+type SettingResponse struct {
+	Key         string      `json:"key"`
+	Type        string      `json:"type"`
+	Value       interface{} `json:"value"`
+	Default     interface{} `json:"default"`
+	Description string      `json:"description"`
+	UpdatedBy   *uuid.UUID  `json:"updated_by"`
+	UpdatedAt   *time.Time  `json:"updated_at"`
+}
+
+type SettingForm struct {
+	Value interface{} `json:"value"`
+}
+
+type SettingRepository interface {
+	GetSettings(ctx context.Context) ([]*Setting, error)
+	SaveSetting(ctx context.Context, setting *Setting, previous interface{}) error
+}
+
+type SettingsServices interface {
+	ListSettings() []*SettingResponse
+	GetSetting(key string) (*SettingResponse, error)
+	UpdateSetting(ctx context.Context, key string, value interface{}, updatedBy uuid.UUID) (*SettingResponse, error)
+	// Subscribe calls fn with the current value right away and again after
+	// every change of the setting
+	Subscribe(key string, fn func(value interface{}))
+
+	Int(key string) int64
+	Float(key string) float64
+	Bool(key string) bool
+	String(key string) string
+	Duration(key string) time.Duration
+	Strings(key string) []string
+}
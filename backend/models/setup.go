@@ -0,0 +1,50 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SetupRoleName is the role of the first user created through the setup
+const SetupRoleName = "super administrator"
+
+var (
+	ErrSetupCompleted    = errors.New("setup has already been completed")
+	ErrInvalidSetupToken = errors.New("invalid setup token")
+)
+
+// SetupState is written once the first super administrator was created, its
+// single row keeps the setup endpoint disabled for good
+type SetupState struct {
+	ID          uint      `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	CompletedBy uuid.UUID `json:"completed_by" gorm:"type:char(36);not null"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// SetupStateID is the primary key of the only SetupState row, a second setup
+// fails on it even when two requests race
+const SetupStateID = 1
+
+type SetupForm struct {
+	Token    string `json:"token" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type SetupRepository interface {
+	// IsSetupRequired is true until setup completed, installations that
+	// already have a super administrator never need it
+	IsSetupRequired(ctx context.Context) (bool, error)
+	// CompleteSetup creates the super administrator and the SetupState row,
+	// user.Password must already be hashed
+	CompleteSetup(ctx context.Context, user *User) error
+}
+
+type SetupServices interface {
+	Required(ctx context.Context) (bool, error)
+	Complete(ctx context.Context, formData *SetupForm) (*User, error)
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a JWT signing key identified by the kid header. Retired keys no
+// longer sign tokens but still verify them until ExpiresAt.
+type SigningKey struct {
+	Kid        string     `json:"kid" gorm:"type:varchar(64);primaryKey"`
+	Algorithm  string     `json:"alg" gorm:"type:varchar(16);not null"`
+	PrivateKey string     `json:"-" gorm:"type:text;not null"`
+	PublicKey  string     `json:"-" gorm:"type:text"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"index"`
+}
+
+// JSONWebKey is the public part of a signing key as published in the JWKS
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type SigningKeyRepository interface {
+	GetSigningKeys(ctx context.Context, now time.Time) ([]*SigningKey, error)
+	CreateSigningKey(ctx context.Context, key *SigningKey) error
+	RetireSigningKey(ctx context.Context, kid string, retiredAt time.Time, expiresAt time.Time) error
+	DeleteExpiredSigningKeys(ctx context.Context, now time.Time) error
+}
+
+type KeyManager interface {
+	Sign(claims jwt.MapClaims) (string, error)
+	Parse(tokenString string) (*jwt.Token, error)
+	JWKS() JSONWebKeySet
+	Rotate(ctx context.Context) error
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	PermissionGrant = "grant"
+	PermissionDeny  = "deny"
+)
+
+// UserPermission grants or denies one permission to a single user on top of
+// the user's role. A denial always wins over the role and over grants.
+type UserPermission struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;uniqueIndex:idx_user_permission"`
+	User       User       `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Permission string     `json:"permission" gorm:"type:varchar(128);not null;uniqueIndex:idx_user_permission"`
+	Effect     string     `json:"effect" gorm:"type:varchar(8);not null"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"index"`
+	CreatedBy  uuid.UUID  `json:"created_by" gorm:"type:char(36)"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Active     bool       `json:"active" gorm:"-"`
+}
+
+type UserPermissionForm struct {
+	Permission string     `json:"permission" validate:"required"`
+	Effect     string     `json:"effect" validate:"required,oneof=grant deny"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// PermissionOverrides are the active grants and denials of the requesting
+// user, policies combine them with the role permissions
+type PermissionOverrides struct {
+	Granted []string
+	Denied  []string
+}
+
+type UserPermissionRepository interface {
+	GetUserPermissions(ctx context.Context, userId uuid.UUID) ([]*UserPermission, error)
+	GetActiveUserPermissions(ctx context.Context, userId uuid.UUID, now time.Time) ([]*UserPermission, error)
+	SaveUserPermission(ctx context.Context, permission *UserPermission) error
+	DeleteUserPermission(ctx context.Context, userId uuid.UUID, permissionId uuid.UUID) (int64, error)
+}
+
+// IsActive reports whether the override still applies
+func (p *UserPermission) IsActive(now time.Time) bool {
+	return p.ExpiresAt == nil || now.Before(*p.ExpiresAt)
+}
+
+// OverridesOf splits the active overrides into grants and denials
+func OverridesOf(permissions []*UserPermission, now time.Time) *PermissionOverrides {
+	overrides := &PermissionOverrides{}
+	for _, permission := range permissions {
+		if !permission.IsActive(now) {
+			continue
+		}
+		if permission.Effect == PermissionDeny {
+			overrides.Denied = append(overrides.Denied, permission.Permission)
+		} else {
+			overrides.Granted = append(overrides.Granted, permission.Permission)
+		}
+	}
+	return overrides
+}
+
+type permissionOverridesKey struct{}
+
+// WithPermissionOverrides attaches the overrides of the user a request acts as
+func WithPermissionOverrides(ctx context.Context, overrides *PermissionOverrides) context.Context {
+	return context.WithValue(ctx, permissionOverridesKey{}, overrides)
+}
+
+// PermissionOverridesFrom returns the overrides attached to the context, nil when there are none
+func PermissionOverridesFrom(ctx context.Context) *PermissionOverrides {
+	overrides, _ := ctx.Value(permissionOverridesKey{}).(*PermissionOverrides)
+	return overrides
+}
+
+func (p *UserPermission) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID = uuid.New()
+	return
+}
@@ -2,12 +2,23 @@ package models
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Account states, a suspended user cannot sign in at all and a blacklisted
+// user can sign in to see their history but cannot book
+const (
+	UserStatusActive      = "active"
+	UserStatusSuspended   = "suspended"
+	UserStatusBlacklisted = "blacklisted"
+)
+
+var ErrAccountSuspended = errors.New("this account is suspended")
+
 type User struct {
 	ID          uuid.UUID      `json:"id" gorm:"type:char(36);primaryKey"`
 	Name        string         `json:"name" gorm:"not null"`
@@ -16,7 +27,21 @@ type User struct {
 	RoleID      uuid.UUID      `json:"role_id" gorm:"not null"`
 	Role        Role           `json:"role" gorm:"foreignKey:RoleID;references:ID;onDelete:cascade"`
 	IsProtected bool           `json:"is_protected" gorm:"default:false"`
-	CreatedAt   time.Time      `json:"created_at"`
+	IsServiceAccount bool      `json:"is_service_account" gorm:"default:false"`
+	Branch      string         `json:"branch" gorm:"type:varchar(64);index"`
+	// EmailVerifiedAt is set once the user proved they own the email address
+	EmailVerifiedAt *time.Time `json:"email_verified_at" gorm:"index"`
+	// PurgedAt is set once a deleted user was anonymised, such a user can no
+	// longer be restored
+	PurgedAt    *time.Time     `json:"purged_at"`
+	// Status is lifted automatically once StatusExpiresAt has passed, use
+	// StatusAt instead of reading it directly
+	Status          string     `json:"status" gorm:"type:varchar(16);not null;default:active;index"`
+	StatusReason    string     `json:"status_reason" gorm:"type:varchar(255)"`
+	StatusExpiresAt *time.Time `json:"status_expires_at"`
+	StatusSetBy     *uuid.UUID `json:"status_set_by" gorm:"type:char(36)"`
+	StatusSetAt     *time.Time `json:"status_set_at"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"index"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
@@ -25,16 +50,21 @@ type UserForm struct {
 	Name  string    `json:"name" validate:"required"`
 	Email string    `json:"email" validate:"required,email,omitempty"`
 	Role  uuid.UUID `json:"role" validate:"required,uuid"`
+	// Branch is left unchanged when it is missing, an empty string removes
+	// the user from their branch
+	Branch *string  `json:"branch" validate:"omitempty,max=64"`
 }
 
+// CreateUserForm has no password, the new user chooses one through the
+// invitation sent to their email
 type CreateUserForm struct {
 	UserForm
-	Password string `json:"password" validate:"required"`
 }
 
+// UpdateUserForm has no password either, admins send a password reset
+// invitation instead
 type UpdateUserForm struct {
 	UserForm
-	Password string `json:"password"`
 }
 
 type UserResponse struct {
@@ -42,14 +72,105 @@ type UserResponse struct {
 	Name     string    `json:"name" gorm:"not null"`
 	Email    string    `json:"email" gorm:"unique;not null"`
 	Password string    `json:"-"`
+	Branch   string    `json:"branch"`
 	Role     RoleResponse `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Status   string    `json:"status"`
+	StatusReason string `json:"status_reason"`
+	StatusExpiresAt *time.Time `json:"status_expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserStatusForm changes the account state. Reason is required unless the
+// account becomes active again, Phone and IDNumber are added to the blacklist
+// together with the email of a blacklisted user.
+type UserStatusForm struct {
+	Status    string     `json:"status" validate:"required,oneof=active suspended blacklisted"`
+	Reason    string     `json:"reason" validate:"required_unless=Status active,max=255"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Phone     string     `json:"phone" validate:"omitempty,max=32"`
+	IDNumber  string     `json:"id_number" validate:"omitempty,max=64"`
+}
+
+// DeletedUserResponse lists a soft deleted user that can still be restored
+type DeletedUserResponse struct {
+	ID        uuid.UUID    `json:"id"`
+	Name      string       `json:"name"`
+	Email     string       `json:"email"`
+	Branch    string       `json:"branch"`
+	Role      RoleResponse `json:"role"`
+	CreatedAt time.Time    `json:"created_at"`
+	DeletedAt time.Time    `json:"deleted_at"`
+}
+
+// UserFilter narrows the user listing, zero values match everything. Search
+// matches part of the name or email, Sort is a key of UserSortColumns.
+type UserFilter struct {
+	Search      string
+	RoleID      uuid.UUID
+	Verified    *bool
+	Status      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        string
+	Desc        bool
+}
+
+// UserImportRow is one line of a CSV import, Role is the name of the role.
+// The field names match UserForm so the user validator messages apply.
+type UserImportRow struct {
+	Name   string `json:"name" validate:"required"`
+	Email  string `json:"email" validate:"required,email"`
+	Role   string `json:"role" validate:"required"`
+	Branch string `json:"branch" validate:"omitempty,max=64"`
+}
+
+// UserImportRowResult reports the problems of one CSV line, keyed by field
+type UserImportRowResult struct {
+	Line   int               `json:"line"`
+	Email  string            `json:"email"`
+	Errors map[string]string `json:"errors"`
+}
+
+// UserImportReport is returned by dry runs as well as real imports, nothing
+// is created while Errors is not empty
+type UserImportReport struct {
+	DryRun         bool                   `json:"dry_run"`
+	Total          int                    `json:"total"`
+	Valid          int                    `json:"valid"`
+	Created        int                    `json:"created"`
+	Invited        int                    `json:"invited"`
+	Errors         []*UserImportRowResult `json:"errors"`
+	InviteFailures []*UserImportRowResult `json:"invite_failures,omitempty"`
+}
+
+// UserSortColumns maps the sort parameter of the user listing to its column
+var UserSortColumns = map[string]string{
+	"name":       "name",
+	"email":      "email",
+	"created_at": "created_at",
 }
 
 type UserRepository interface {
-	GetAllUser(ctx context.Context) ([]*UserResponse, error)
-	CreateUser(ctx context.Context, formData *CreateUserForm) error
+	GetAllUser(ctx context.Context, filter *UserFilter, page *PageQuery) (*Page, error)
+	CreateUser(ctx context.Context, formData *CreateUserForm) (*User, error)
 	UpdateUser(ctx context.Context, updateData map[string]interface{}, userId uuid.UUID) error
 	DeleteUser(ctx context.Context, userId uuid.UUID) error
+	GetDeletedUsers(ctx context.Context, page *PageQuery) (*Page, error)
+	RestoreUser(ctx context.Context, userId uuid.UUID) error
+	PurgeUser(ctx context.Context, userId uuid.UUID) error
+	ExportUsers(ctx context.Context, filter *UserFilter, batchSize int, fn func(users []*UserResponse) error) error
+	GetTakenEmails(ctx context.Context, emails []string) (map[string]bool, error)
+	ImportUsers(ctx context.Context, users []*User) error
+	SetUserStatus(ctx context.Context, userId uuid.UUID, formData *UserStatusForm, setBy uuid.UUID) error
+}
+
+// StatusAt is the state of the account at now, taking the expiry into account
+func (u *User) StatusAt(now time.Time) string {
+	if u.Status == "" || u.StatusExpiresAt != nil && !now.Before(*u.StatusExpiresAt) {
+		return UserStatusActive
+	}
+	return u.Status
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -2,7 +2,6 @@ package policy
 
 import (
 	"context"
-	"strings"
 
 	"github.com/DestaAri1/RentAuto/models"
 	"github.com/google/uuid"
@@ -18,32 +17,34 @@ func NewAdminPolicy(policy *Policy) *AdminPolicy {
 	}
 }
 
-// IsAdmin checks if the provided roleId belongs to an administrator
+// IsAdmin checks if the provided roleId grants any administrative permission
 func (p *AdminPolicy) IsAdmin(ctx context.Context, roleId uuid.UUID) (bool, error) {
-	roles, err := p.roleRepo.GetRoles(ctx)
+	permissions, err := p.EffectivePermissions(ctx, roleId)
 	if err != nil {
 		return false, err
 	}
 
-	// Find the role by ID
-	var targetRole *models.RoleResponse
-	for _, role := range roles {
-		if role.ID == roleId {
-			targetRole = role
-			break
+	for _, group := range Registry {
+		if !group.Administrative {
+			continue
+		}
+		for _, permission := range group.Permissions {
+			if allows(ctx, permissions, permission) {
+				return true, nil
+			}
 		}
 	}
 
-	if targetRole == nil {
-		return false, ErrRoleNotFound
-	}
-
-	// Check if the role is administrator
-	return strings.ToLower(targetRole.Name) == "administrator" || strings.ToLower(targetRole.Name) == "super administrator", nil
+	return false, nil
 }
 
-// RequireAdmin ensures the user has administrator role
+// RequireAdmin ensures the role grants administrative permissions
 func (p *AdminPolicy) RequireAdmin(ctx context.Context, roleId uuid.UUID) error {
+	// Administration is never delegated to API keys
+	if _, scoped := models.APIKeyScopes(ctx); scoped {
+		return ErrUnauthorized
+	}
+
 	isAdmin, err := p.IsAdmin(ctx, roleId)
 	if err != nil {
 		return err
@@ -56,22 +57,22 @@ func (p *AdminPolicy) RequireAdmin(ctx context.Context, roleId uuid.UUID) error
 	return nil
 }
 
-// CanManageRoles checks if a role can manage roles (admin only)
+// CanManageRoles checks if a role can manage roles
 func (p *AdminPolicy) CanManageRoles(ctx context.Context, roleId uuid.UUID) error {
-	return p.RequireAdmin(ctx, roleId)
+	return p.CheckPermission(ctx, roleId, PermRoleManage)
 }
 
-// CanManageUsers checks if a role can manage users (admin only)
+// CanManageUsers checks if a role can manage users
 func (p *AdminPolicy) CanManageUsers(ctx context.Context, roleId uuid.UUID) error {
-	return p.RequireAdmin(ctx, roleId)
+	return p.CheckPermission(ctx, roleId, PermUserManage)
 }
 
-// CanViewSystemLogs checks if a role can view system logs (admin only)
+// CanViewSystemLogs checks if a role can view system logs
 func (p *AdminPolicy) CanViewSystemLogs(ctx context.Context, roleId uuid.UUID) error {
-	return p.RequireAdmin(ctx, roleId)
+	return p.CheckPermission(ctx, roleId, PermSystemLogs)
 }
 
-// CanManageSystemSettings checks if a role can manage system settings (admin only)
+// CanManageSystemSettings checks if a role can manage system settings
 func (p *AdminPolicy) CanManageSystemSettings(ctx context.Context, roleId uuid.UUID) error {
-	return p.RequireAdmin(ctx, roleId)
-}
\ No newline at end of file
+	return p.CheckPermission(ctx, roleId, PermSystemSettings)
+}
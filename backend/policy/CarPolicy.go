@@ -3,6 +3,7 @@ package policy
 import (
 	"context"
 
+	"github.com/DestaAri1/RentAuto/models"
 	"github.com/google/uuid"
 )
 
@@ -17,20 +18,20 @@ func NewCarPolicy(policy *Policy) *CarPolicy {
 }
 
 func (p *CarPolicy) CanEditCar(ctx context.Context, roleId uuid.UUID) error {
-	return p.CheckPermission(ctx, roleId, "edit_car")
+	return p.CheckPermission(ctx, roleId, PermCarUpdate)
 }
 
 // CanCreateCar checks if a role can create cars
 func (p *CarPolicy) CanCreateCar(ctx context.Context, roleId uuid.UUID) error {
-	return p.CheckPermission(ctx, roleId, "create_car")
+	return p.CheckPermission(ctx, roleId, PermCarCreate)
 }
 
-// CanUpdateCar checks if a role can update cars
-func (p *CarPolicy) CanUpdateCar(ctx context.Context, roleId uuid.UUID) error {
-	return p.CheckPermission(ctx, roleId, "update_car")
+// CanUpdateCar checks if the subject can update the car and its units
+func (p *CarPolicy) CanUpdateCar(ctx context.Context, subject Subject, car *models.CarParent) error {
+	return p.Authorize(ctx, subject, PermCarUpdate, car)
 }
 
-// CanDeleteCar checks if a role can delete cars
-func (p *CarPolicy) CanDeleteCar(ctx context.Context, roleId uuid.UUID) error {
-	return p.CheckPermission(ctx, roleId, "delete_car")
+// CanDeleteCar checks if the subject can delete the car and its units
+func (p *CarPolicy) CanDeleteCar(ctx context.Context, subject Subject, car *models.CarParent) error {
+	return p.Authorize(ctx, subject, PermCarDelete, car)
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package policy
 import (
 	"context"
 
+	"github.com/DestaAri1/RentAuto/models"
 	"github.com/google/uuid"
 )
 
@@ -18,15 +19,15 @@ func NewCarTypesPolicy(policy *Policy) *CarTypesPolicy {
 
 // CanCreateCar checks if a role can create cars
 func (p *CarTypesPolicy) CanCreateCarTypes(ctx context.Context, roleId uuid.UUID) error {
-	return p.CheckPermission(ctx, roleId, "create_car_types")
+	return p.CheckPermission(ctx, roleId, PermCarTypeCreate)
 }
 
-// CanUpdateCar checks if a role can update cars
-func (p *CarTypesPolicy) CanUpdateCarTypes(ctx context.Context, roleId uuid.UUID) error {
-	return p.CheckPermission(ctx, roleId, "update_car_types")
+// CanUpdateCarTypes checks if the subject can update the car type
+func (p *CarTypesPolicy) CanUpdateCarTypes(ctx context.Context, subject Subject, carType *models.CarTypes) error {
+	return p.Authorize(ctx, subject, PermCarTypeUpdate, carType)
 }
 
-// CanDeleteCar checks if a role can delete cars
-func (p *CarTypesPolicy) CanDeleteCarTypes(ctx context.Context, roleId uuid.UUID) error {
-	return p.CheckPermission(ctx, roleId, "delete_car_types")
+// CanDeleteCarTypes checks if the subject can delete the car type
+func (p *CarTypesPolicy) CanDeleteCarTypes(ctx context.Context, subject Subject, carType *models.CarTypes) error {
+	return p.Authorize(ctx, subject, PermCarTypeDelete, carType)
 }
\ No newline at end of file
@@ -0,0 +1,274 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permissions are named resource.action. Policies only check these constants,
+// so a permission that is not registered here can never be granted. Roles may
+// also hold wildcards: "all" or "*", "car.*" (also written "car:*") and "*.view".
+// Some permissions can be narrowed with a scope, "car.update.own" only allows
+// editing the cars the user created, see Scoped and Policy.Authorize.
+const (
+	PermissionAll      = "all"
+	PermissionWildcard = "*"
+
+	PermCarView   = "car.view"
+	PermCarCreate = "car.create"
+	PermCarUpdate = "car.update"
+	PermCarDelete = "car.delete"
+
+	PermCarTypeView   = "car_type.view"
+	PermCarTypeCreate = "car_type.create"
+	PermCarTypeUpdate = "car_type.update"
+	PermCarTypeDelete = "car_type.delete"
+
+	PermRoleManage      = "role.manage"
+	PermUserManage      = "user.manage"
+	PermUserImpersonate = "user.impersonate"
+
+	PermSystemLogs     = "system.logs"
+	PermSystemSettings = "system.settings"
+)
+
+// Scopes narrow a permission to the resources a user owns or that belong to
+// the user's branch
+const (
+	ScopeOwn    = "own"
+	ScopeBranch = "branch"
+)
+
+var scopeLabels = map[string]string{
+	ScopeOwn:    "only those they created",
+	ScopeBranch: "only those of their branch",
+}
+
+// PermissionGroup is the set of permissions of one resource. Administrative
+// groups cannot be delegated to API keys. Scopes lists the scopes each
+// permission may be narrowed to.
+type PermissionGroup struct {
+	Resource       string              `json:"resource"`
+	Label          string              `json:"label"`
+	Permissions    []string            `json:"permissions"`
+	Description    map[string]string   `json:"description"`
+	Scopes         map[string][]string `json:"scopes,omitempty"`
+	Administrative bool                `json:"administrative"`
+}
+
+// Registry lists every permission grouped by resource, in display order
+var Registry = []PermissionGroup{
+	{
+		Resource:    "car",
+		Label:       "Car Management",
+		Permissions: []string{PermCarView, PermCarCreate, PermCarUpdate, PermCarDelete},
+		Description: map[string]string{
+			PermCarView:   "View cars and their units",
+			PermCarCreate: "Add cars and units",
+			PermCarUpdate: "Edit cars and units",
+			PermCarDelete: "Delete cars and units",
+		},
+		Scopes: map[string][]string{
+			PermCarUpdate: {ScopeOwn, ScopeBranch},
+			PermCarDelete: {ScopeOwn, ScopeBranch},
+		},
+	},
+	{
+		Resource:    "car_type",
+		Label:       "Car Type",
+		Permissions: []string{PermCarTypeView, PermCarTypeCreate, PermCarTypeUpdate, PermCarTypeDelete},
+		Description: map[string]string{
+			PermCarTypeView:   "View car types",
+			PermCarTypeCreate: "Add car types",
+			PermCarTypeUpdate: "Edit car types",
+			PermCarTypeDelete: "Delete car types",
+		},
+		Scopes: map[string][]string{
+			PermCarTypeUpdate: {ScopeOwn},
+			PermCarTypeDelete: {ScopeOwn},
+		},
+	},
+	{
+		Resource:    "role",
+		Label:       "Role Management",
+		Permissions: []string{PermRoleManage},
+		Description: map[string]string{
+			PermRoleManage: "Create, edit and delete roles",
+		},
+		Administrative: true,
+	},
+	{
+		Resource:    "user",
+		Label:       "User Management",
+		Permissions: []string{PermUserManage, PermUserImpersonate},
+		Description: map[string]string{
+			PermUserManage:      "Manage users, their sessions and service accounts",
+			PermUserImpersonate: "Act as a customer to see what they see, for support",
+		},
+		Administrative: true,
+	},
+	{
+		Resource:    "system",
+		Label:       "System",
+		Permissions: []string{PermSystemLogs, PermSystemSettings},
+		Description: map[string]string{
+			PermSystemLogs:     "View system and audit logs",
+			PermSystemSettings: "Change system settings",
+		},
+		Administrative: true,
+	},
+}
+
+// legacyPermissions maps the names stored by older roles to their canonical name
+var legacyPermissions = map[string]string{
+	"view_car":         PermCarView,
+	"edit_car":         PermCarUpdate,
+	"create_car":       PermCarCreate,
+	"update_car":       PermCarUpdate,
+	"delete_car":       PermCarDelete,
+	"create_car_types": PermCarTypeCreate,
+	"update_car_types": PermCarTypeUpdate,
+	"delete_car_types": PermCarTypeDelete,
+}
+
+var (
+	registered     = map[string]bool{}
+	administrative = map[string]bool{}
+	resources      = map[string]bool{}
+	actions        = map[string]bool{}
+	scopes         = map[string][]string{}
+)
+
+func init() {
+	for _, group := range Registry {
+		resources[group.Resource] = true
+		for _, permission := range group.Permissions {
+			register(permission, group.Administrative)
+
+			// Scoped variants are granted like any other permission
+			for _, scope := range group.Scopes[permission] {
+				scoped := Scoped(permission, scope)
+				register(scoped, group.Administrative)
+				scopes[permission] = append(scopes[permission], scope)
+				group.Description[scoped] = group.Description[permission] + ", " + scopeLabels[scope]
+			}
+		}
+	}
+}
+
+func register(permission string, isAdministrative bool) {
+	registered[permission] = true
+	administrative[permission] = isAdministrative
+	_, action, _ := strings.Cut(permission, ".")
+	actions[action] = true
+}
+
+// Scoped names the permission narrowed to scope, for example "car.update.own"
+func Scoped(permission string, scope string) string {
+	return permission + "." + scope
+}
+
+// Unscoped strips the scope from a scoped permission such as "car.update.own"
+func Unscoped(permission string) string {
+	parts := strings.SplitN(permission, ".", 3)
+	if len(parts) < 3 {
+		return permission
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// ScopesOf lists the scopes a permission may be narrowed to
+func ScopesOf(permission string) []string {
+	canonical, _ := CanonicalPermission(permission)
+	return scopes[canonical]
+}
+
+// CanonicalPermission resolves a permission, legacy alias or wildcard to its
+// canonical form. ok is false for anything that matches no registered permission.
+func CanonicalPermission(permission string) (string, bool) {
+	permission = strings.ToLower(strings.TrimSpace(permission))
+
+	if permission == PermissionAll || permission == PermissionWildcard {
+		return PermissionAll, true
+	}
+	if canonical, ok := legacyPermissions[permission]; ok {
+		return canonical, true
+	}
+
+	permission = strings.Replace(permission, ":", ".", 1)
+	if registered[permission] {
+		return permission, true
+	}
+
+	resource, action, found := strings.Cut(permission, ".")
+	if !found {
+		return permission, false
+	}
+	if action == PermissionWildcard && resources[resource] {
+		return permission, true
+	}
+	if resource == PermissionWildcard && actions[action] {
+		return permission, true
+	}
+
+	return permission, false
+}
+
+// MatchPermission reports whether a granted canonical permission, possibly a
+// wildcard, covers the required one
+func MatchPermission(granted string, required string) bool {
+	if granted == PermissionAll || granted == required {
+		return true
+	}
+
+	grantedResource, grantedAction, ok := strings.Cut(granted, ".")
+	if !ok {
+		return false
+	}
+	requiredResource, requiredAction, ok := strings.Cut(required, ".")
+	if !ok {
+		return false
+	}
+
+	return (grantedResource == PermissionWildcard || grantedResource == requiredResource) &&
+		(grantedAction == PermissionWildcard || grantedAction == requiredAction)
+}
+
+// IsKnownPermission reports whether a single permission is registered,
+// wildcards excluded
+func IsKnownPermission(permission string) bool {
+	canonical, _ := CanonicalPermission(permission)
+	return registered[canonical]
+}
+
+// IsAdministrative reports whether a permission belongs to an administrative group
+func IsAdministrative(permission string) bool {
+	canonical, _ := CanonicalPermission(permission)
+	return administrative[canonical]
+}
+
+// NormalizePermissions validates permissions for a role and returns them in
+// canonical form without duplicates
+func NormalizePermissions(permissions []string) ([]string, error) {
+	normalized := make([]string, 0, len(permissions))
+	seen := map[string]bool{}
+	var unknown []string
+
+	for _, permission := range permissions {
+		canonical, ok := CanonicalPermission(permission)
+		if !ok {
+			unknown = append(unknown, permission)
+			continue
+		}
+		if !seen[canonical] {
+			seen[canonical] = true
+			normalized = append(normalized, canonical)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown permissions: %s", strings.Join(unknown, ", "))
+	}
+
+	return normalized, nil
+}
@@ -3,11 +3,11 @@ package policy
 import (
 	"context"
 	"errors"
-	"fmt"
-	"strings"
+	"time"
 
 	"github.com/DestaAri1/RentAuto/models"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 var (
@@ -16,48 +16,184 @@ var (
 )
 
 type Policy struct {
-	roleRepo models.RoleRepository
+	roleRepo        models.RoleRepository
+	userPermissions models.UserPermissionRepository
 }
 
-func NewPolicy(roleRepo models.RoleRepository) *Policy {
+func NewPolicy(roleRepo models.RoleRepository, userPermissions models.UserPermissionRepository) *Policy {
 	return &Policy{
-		roleRepo: roleRepo,
+		roleRepo:        roleRepo,
+		userPermissions: userPermissions,
 	}
 }
 
 // CheckPermission checks if a role has the required permission
 func (p *Policy) CheckPermission(ctx context.Context, roleId uuid.UUID, requiredPermission string) error {
-	roles, err := p.roleRepo.GetRoles(ctx)
+	permissions, err := p.EffectivePermissions(ctx, roleId)
 	if err != nil {
 		return err
 	}
 
-	// Find the role by ID
-	var targetRole *models.RoleResponse
-	for _, role := range roles {
-		if role.ID == roleId {
-			targetRole = role
-			break
+	// API keys never exceed their scopes, whatever the role allows
+	if scopes, ok := models.APIKeyScopes(ctx); ok && !hasPermission(scopes, requiredPermission) {
+		return ErrUnauthorized
+	}
+
+	// Check if the role or the user's grants allow it, wildcards included
+	if allows(ctx, permissions, requiredPermission) {
+		return nil
+	}
+
+	return ErrUnauthorized
+}
+
+// HoldsAll reports whether roleId holds every one of permissions, the user's
+// overrides in ctx included. Wildcards are only held through an equal or
+// wider wildcard.
+func (p *Policy) HoldsAll(ctx context.Context, roleId uuid.UUID, permissions []string) (bool, error) {
+	for _, permission := range permissions {
+		err := p.CheckPermission(ctx, roleId, permission)
+		if errors.Is(err, ErrUnauthorized) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
 		}
 	}
+	return true, nil
+}
 
-	fmt.Println(targetRole)
+// CanAssignRole is true when roleId holds every effective permission of
+// targetRoleId, so assigning it never grants more than the assigner has
+func (p *Policy) CanAssignRole(ctx context.Context, roleId uuid.UUID, targetRoleId uuid.UUID) (bool, error) {
+	permissions, err := p.EffectivePermissions(ctx, targetRoleId)
+	if err != nil {
+		return false, err
+	}
+	return p.HoldsAll(ctx, roleId, permissions)
+}
 
-	if targetRole == nil {
-		return ErrRoleNotFound
+// WithUserOverrides attaches the active grants and denials of userId, checks
+// made with the returned context act as that user. The auth middleware does
+// this for every request.
+func (p *Policy) WithUserOverrides(ctx context.Context, userId uuid.UUID) (context.Context, error) {
+	now := time.Now()
+	permissions, err := p.userPermissions.GetActiveUserPermissions(ctx, userId, now)
+	if err != nil {
+		return ctx, err
 	}
+	return models.WithPermissionOverrides(ctx, models.OverridesOf(permissions, now)), nil
+}
 
-	// Administrator has all permissions
-	if strings.ToLower(targetRole.Name) == "administrator" {
-		return nil
+// findRole looks the role up through the repository, which is served from
+// memory when the cached role repository is used
+func (p *Policy) findRole(ctx context.Context, roleId uuid.UUID) (*models.RoleResponse, error) {
+	role, err := p.roleRepo.GetRole(ctx, roleId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// EffectivePermissions returns the role's permissions together with those of
+// every role it inherits from. Cycles are refused when roles are saved, the
+// walk still visits each role once so a cycle can never loop.
+func (p *Policy) EffectivePermissions(ctx context.Context, roleId uuid.UUID) ([]string, error) {
+	permissions, _, err := p.resolveRole(ctx, roleId)
+	return permissions, err
+}
+
+// DescribeRole returns the effective permissions of a role and the roles
+// they are inherited from, nearest first
+func (p *Policy) DescribeRole(ctx context.Context, roleId uuid.UUID) (*models.EffectivePermissions, error) {
+	permissions, ancestors, err := p.resolveRole(ctx, roleId)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if the role has the required permission
-	for _, permission := range targetRole.Permission {
-		if strings.ToLower(permission) == strings.ToLower(requiredPermission) {
-			return nil
+	role, err := p.findRole(ctx, roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EffectivePermissions{
+		Role:          role,
+		Permissions:   permissions,
+		InheritedFrom: ancestors,
+	}, nil
+}
+
+func (p *Policy) resolveRole(ctx context.Context, roleId uuid.UUID) ([]string, []*models.RoleResponse, error) {
+	role, err := p.findRole(ctx, roleId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var permissions []string
+	var ancestors []*models.RoleResponse
+	seenPermission := map[string]bool{}
+	visited := map[uuid.UUID]bool{role.ID: true}
+
+	queue := []*models.RoleResponse{role}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, permission := range current.Permission {
+			if !seenPermission[permission] {
+				seenPermission[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+
+		for _, parentId := range current.Parents {
+			if visited[parentId] {
+				continue
+			}
+			visited[parentId] = true
+
+			parent, err := p.findRole(ctx, parentId)
+			if errors.Is(err, ErrRoleNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+
+			ancestors = append(ancestors, parent)
+			queue = append(queue, parent)
 		}
 	}
 
-	return ErrUnauthorized
+	return permissions, ancestors, nil
+}
+
+// allows combines the role permissions with the user's overrides, a denial
+// wins over everything and also covers the scoped variants of a permission
+func allows(ctx context.Context, permissions []string, requiredPermission string) bool {
+	overrides := models.PermissionOverridesFrom(ctx)
+	if overrides == nil {
+		return hasPermission(permissions, requiredPermission)
+	}
+
+	required, _ := CanonicalPermission(requiredPermission)
+	if hasPermission(overrides.Denied, required) || hasPermission(overrides.Denied, Unscoped(required)) {
+		return false
+	}
+
+	return hasPermission(permissions, required) || hasPermission(overrides.Granted, required)
+}
+
+// hasPermission compares canonical names so roles saved with legacy names keep working
+func hasPermission(permissions []string, requiredPermission string) bool {
+	required, _ := CanonicalPermission(requiredPermission)
+	for _, permission := range permissions {
+		if granted, _ := CanonicalPermission(permission); MatchPermission(granted, required) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DestaAri1/RentAuto/models"
+)
+
+func TestCanonicalPermission(t *testing.T) {
+	tests := []struct {
+		permission string
+		want       string
+		wantOK     bool
+	}{
+		{"car.view", PermCarView, true},
+		{" Car.View ", PermCarView, true},
+		{"car:view", PermCarView, true},
+		{"car.update.own", "car.update.own", true},
+		{"view_car", PermCarView, true},
+		{"edit_car", PermCarUpdate, true},
+		{"delete_car_types", PermCarTypeDelete, true},
+		{"all", PermissionAll, true},
+		{"*", PermissionAll, true},
+		{"car.*", "car.*", true},
+		{"car:*", "car.*", true},
+		{"*.view", "*.view", true},
+		{"boat.*", "boat.*", false},
+		{"*.sail", "*.sail", false},
+		{"car.sail", "car.sail", false},
+		{"car.view.own", "car.view.own", false},
+		{"order.view", "order.view", false},
+		{"car", "car", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := CanonicalPermission(tt.permission)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("CanonicalPermission(%q) = (%q, %v), want (%q, %v)", tt.permission, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  []string
+		required string
+		want     bool
+	}{
+		{name: "exact", granted: []string{PermCarView}, required: PermCarView, want: true},
+		{name: "other permission", granted: []string{PermCarView}, required: PermCarUpdate},
+		{name: "nothing granted", granted: nil, required: PermCarView},
+		{name: "all", granted: []string{"all"}, required: PermSystemSettings, want: true},
+		{name: "star", granted: []string{"*"}, required: PermRoleManage, want: true},
+		{name: "resource wildcard", granted: []string{"car.*"}, required: PermCarDelete, want: true},
+		{name: "resource wildcard with colon", granted: []string{"car:*"}, required: PermCarDelete, want: true},
+		{name: "resource wildcard of another resource", granted: []string{"car.*"}, required: PermCarTypeDelete},
+		{name: "resource wildcard covers scoped", granted: []string{"car.*"}, required: "car.update.own", want: true},
+		{name: "action wildcard", granted: []string{"*.view"}, required: PermCarTypeView, want: true},
+		{name: "action wildcard of another action", granted: []string{"*.view"}, required: PermCarUpdate},
+		{name: "legacy alias granted", granted: []string{"edit_car"}, required: PermCarUpdate, want: true},
+		{name: "legacy alias required", granted: []string{PermCarUpdate}, required: "update_car", want: true},
+		{name: "unscoped does not cover scoped", granted: []string{PermCarUpdate}, required: "car.update.own"},
+		{name: "scoped does not cover unscoped", granted: []string{"car.update.own"}, required: PermCarUpdate},
+		{name: "scoped exact", granted: []string{"car.update.branch"}, required: "car.update.branch", want: true},
+		{name: "other scope", granted: []string{"car.update.branch"}, required: "car.update.own"},
+		{name: "case and spaces", granted: []string{" CAR.VIEW "}, required: PermCarView, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasPermission(tt.granted, tt.required); got != tt.want {
+				t.Fatalf("hasPermission(%v, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowsWithOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      []string
+		overrides *models.PermissionOverrides
+		required  string
+		want      bool
+	}{
+		{name: "no overrides", role: []string{PermCarView}, required: PermCarView, want: true},
+		{name: "grant adds a permission", role: nil, overrides: &models.PermissionOverrides{Granted: []string{PermCarUpdate}}, required: PermCarUpdate, want: true},
+		{name: "deny removes a role permission", role: []string{PermCarUpdate}, overrides: &models.PermissionOverrides{Denied: []string{PermCarUpdate}}, required: PermCarUpdate},
+		{name: "deny beats a wildcard", role: []string{"all"}, overrides: &models.PermissionOverrides{Denied: []string{PermUserManage}}, required: PermUserManage},
+		{name: "deny beats a grant", role: nil, overrides: &models.PermissionOverrides{Granted: []string{PermCarDelete}, Denied: []string{PermCarDelete}}, required: PermCarDelete},
+		{name: "deny covers the scoped variants", role: []string{"car.update.own"}, overrides: &models.PermissionOverrides{Denied: []string{PermCarUpdate}}, required: "car.update.own"},
+		{name: "scoped deny leaves the other scope", role: []string{"car.update.branch"}, overrides: &models.PermissionOverrides{Denied: []string{"car.update.own"}}, required: "car.update.branch", want: true},
+		{name: "wildcard deny", role: []string{PermCarView}, overrides: &models.PermissionOverrides{Denied: []string{"car.*"}}, required: PermCarView},
+		{name: "deny of another permission", role: []string{PermCarView}, overrides: &models.PermissionOverrides{Denied: []string{PermCarDelete}}, required: PermCarView, want: true},
+		{name: "legacy alias deny", role: []string{PermCarUpdate}, overrides: &models.PermissionOverrides{Denied: []string{"edit_car"}}, required: PermCarUpdate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.overrides != nil {
+				ctx = models.WithPermissionOverrides(ctx, tt.overrides)
+			}
+
+			if got := allows(ctx, tt.role, tt.required); got != tt.want {
+				t.Fatalf("allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
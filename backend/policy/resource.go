@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// Subject is the user a request acts as
+type Subject struct {
+	UserID uuid.UUID
+	RoleID uuid.UUID
+	Branch string
+}
+
+// Resource is a loaded record that scoped permissions are evaluated against
+type Resource interface {
+	// OwnerID is the user who created the record
+	OwnerID() uuid.UUID
+	// BranchName is the branch the record belongs to, empty when it has none
+	BranchName() string
+}
+
+// Authorize checks a permission against a loaded resource. The plain
+// permission allows acting on any resource, its scoped variants only on the
+// resources the subject created or that belong to the subject's branch.
+func (p *Policy) Authorize(ctx context.Context, subject Subject, permission string, resource Resource) error {
+	err := p.CheckPermission(ctx, subject.RoleID, permission)
+	if !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+
+	for _, scope := range ScopesOf(permission) {
+		if !scopeApplies(scope, subject, resource) {
+			continue
+		}
+
+		err := p.CheckPermission(ctx, subject.RoleID, Scoped(permission, scope))
+		if !errors.Is(err, ErrUnauthorized) {
+			return err
+		}
+	}
+
+	return ErrUnauthorized
+}
+
+// CheckAnyScope passes when the role holds the permission in any scope. It
+// guards routes that cannot know the resource yet, the handler then narrows
+// the check with Authorize once the resource is loaded.
+func (p *Policy) CheckAnyScope(ctx context.Context, roleId uuid.UUID, permission string) error {
+	err := p.CheckPermission(ctx, roleId, permission)
+	if !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+
+	for _, scope := range ScopesOf(permission) {
+		err := p.CheckPermission(ctx, roleId, Scoped(permission, scope))
+		if !errors.Is(err, ErrUnauthorized) {
+			return err
+		}
+	}
+
+	return ErrUnauthorized
+}
+
+func scopeApplies(scope string, subject Subject, resource Resource) bool {
+	switch scope {
+	case ScopeOwn:
+		return subject.UserID != uuid.Nil && resource.OwnerID() == subject.UserID
+	case ScopeBranch:
+		return subject.Branch != "" && resource.BranchName() == subject.Branch
+	}
+	return false
+}
@@ -96,6 +96,24 @@ func (r *CarChildRepository) GetOneCarChild(ctx context.Context, carChildSlug st
 	return carChildResponse, nil
 }
 
+// GetCarParent loads a car for policies that check ownership or branch
+func (r *CarChildRepository) GetCarParent(ctx context.Context, carParentId uuid.UUID) (*models.CarParent, error) {
+	carParent := &models.CarParent{}
+	if err := r.db.WithContext(ctx).First(carParent, "id = ?", carParentId).Error; err != nil {
+		return nil, err
+	}
+	return carParent, nil
+}
+
+// GetCarChildParent loads the car a unit belongs to, units are authorized through it
+func (r *CarChildRepository) GetCarChildParent(ctx context.Context, carChildId uuid.UUID) (*models.CarParent, error) {
+	carChild := &models.CarChild{}
+	if err := r.db.WithContext(ctx).Preload("CarParent").First(carChild, "id = ?", carChildId).Error; err != nil {
+		return nil, err
+	}
+	return &carChild.CarParent, nil
+}
+
 func (r *CarChildRepository) CreateCarChild(ctx context.Context, formData *models.FormCarChild, userId uuid.UUID) error {
 	// Validate required fields first
 	if formData == nil {
@@ -147,6 +165,11 @@ func (r *CarChildRepository) CreateCarChild(ctx context.Context, formData *model
 		return res.Error
 	}
 
+	if err := recordAudit(ctx, tx, models.AuditCreate, models.AuditEntityCarChild, carChild.ID.ID, nil, carChild); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return err
@@ -238,7 +261,7 @@ func (r *CarChildRepository) UpdateCarChild(ctx context.Context, updateData map[
 
 	// Handle image deletion if new image is provided
 	if newImage, ok := updateData["image_url"].(string); ok && newImage != "" && currentCarChild.ImageURL != "" && currentCarChild.ImageURL != "default.png" {
-		oldImagePath := filepath.Join("assets/car", currentCarChild.ImageURL)
+		oldImagePath := filepath.Join(utils.CarAssetsDir, currentCarChild.ImageURL)
 		if err := utils.DeleteFile(oldImagePath); err != nil {
 			// Log error but don't fail the transaction for file deletion
 			// You might want to implement proper logging here
@@ -258,6 +281,17 @@ func (r *CarChildRepository) UpdateCarChild(ctx context.Context, updateData map[
 		return gorm.ErrRecordNotFound
 	}
 
+	var updatedCarChild models.CarChild
+	if err := tx.First(&updatedCarChild, "id = ?", carChildId).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, models.AuditUpdate, models.AuditEntityCarChild, carChildId, &currentCarChild, &updatedCarChild); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
@@ -394,6 +428,17 @@ func (r *CarChildRepository) UpdateStatusCarChild(ctx context.Context, updateDat
 		return errors.New("no rows were updated")
 	}
 
+	var updatedCarChild models.CarChild
+	if err := tx.First(&updatedCarChild, "id = ?", carChildId).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, models.AuditStatus, models.AuditEntityCarChild, carChildId, &carChild, &updatedCarChild); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return err
@@ -451,6 +496,11 @@ func (r *CarChildRepository) DeleteCarChild(ctx context.Context, carChildId uuid
 		tx.Rollback()
 		return gorm.ErrRecordNotFound
 	}
+
+	if err := recordAudit(ctx, tx, models.AuditDelete, models.AuditEntityCarChild, carChildId, &carChild, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
 	
 	if err := tx.Commit().Error; err != nil {
 		return err
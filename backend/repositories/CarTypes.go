@@ -29,6 +29,15 @@ func (r *CarTypesRepository) GetCarType(ctx context.Context) ([]*models.CarTypeR
 	return carTypes, nil
 }
 
+// GetCarTypeByID loads a car type for policies that check ownership
+func (r *CarTypesRepository) GetCarTypeByID(ctx context.Context, carTypeId uuid.UUID) (*models.CarTypes, error) {
+	carType := &models.CarTypes{}
+	if err := r.db.WithContext(ctx).First(carType, "id = ?", carTypeId).Error; err != nil {
+		return nil, err
+	}
+	return carType, nil
+}
+
 func (r *CarTypesRepository) CreateCarType(ctx context.Context, formData *models.FormCarTypes, userId uuid.UUID) error {
 	carType := &models.CarTypes{
 		Name:   formData.Name,
@@ -42,6 +51,11 @@ func (r *CarTypesRepository) CreateCarType(ctx context.Context, formData *models
 		return res.Error
 	}
 
+	if err := recordAudit(ctx, tx, models.AuditCreate, models.AuditEntityCarType, carType.ID, nil, carType); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	tx.Commit()
 
 	return nil
@@ -50,23 +64,51 @@ func (r *CarTypesRepository) CreateCarType(ctx context.Context, formData *models
 func (r *CarTypesRepository) UpdateCarType(ctx context.Context, updateData map[string]interface{}, carTypeId uuid.UUID, userId uuid.UUID) error {
 	tx := r.db.Begin()
 
+	var currentCarType models.CarTypes
+	if err := tx.First(&currentCarType, "id = ?", carTypeId).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if res := tx.Model(&models.CarTypes{}).Where("id = ? AND deleted_at IS NULL", carTypeId).Updates(updateData); res.Error != nil {
 		tx.Rollback()
 		return res.Error
 	}
 
+	var updatedCarType models.CarTypes
+	if err := tx.First(&updatedCarType, "id = ?", carTypeId).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, models.AuditUpdate, models.AuditEntityCarType, carTypeId, &currentCarType, &updatedCarType); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	tx.Commit()
 	return nil
 }
 
 func (r *CarTypesRepository) DeleteCarType(ctx context.Context, carTypeId uuid.UUID) error {
 	tx := r.db.Begin()
+
+	var carType models.CarTypes
+	if err := tx.First(&carType, "id = ?", carTypeId).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
 	
 	// Soft delete menggunakan gorm
 	if res := tx.Where("id = ?", carTypeId).Delete(&models.CarTypes{}); res.Error != nil {
 		tx.Rollback()
 		return res.Error
 	}
+
+	if err := recordAudit(ctx, tx, models.AuditDelete, models.AuditEntityCarType, carTypeId, &carType, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
 	
 	tx.Commit()
 	return nil
@@ -4,10 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/DestaAri1/RentAuto/models"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -19,35 +20,139 @@ func NewUserRepository(db *gorm.DB) models.UserRepository{
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) GetAllUser(ctx context.Context) ([]*models.UserResponse, error) {
+// filterUsers applies the filter to the active customers and staff, service
+// accounts are listed separately
+func (r *UserRepository) filterUsers(ctx context.Context, filter *models.UserFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.User{}).Where("deleted_at IS NULL AND is_service_account = ?", false)
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(search)) + "%"
+		query = query.Where("(LOWER(name) LIKE ? OR LOWER(email) LIKE ?)", pattern, pattern)
+	}
+	if filter.RoleID != uuid.Nil {
+		query = query.Where("role_id = ?", filter.RoleID)
+	}
+	if filter.Verified != nil {
+		if *filter.Verified {
+			query = query.Where("email_verified_at IS NOT NULL")
+		} else {
+			query = query.Where("email_verified_at IS NULL")
+		}
+	}
+	// A status past its expiry counts as active, see User.StatusAt
+	switch filter.Status {
+	case "":
+	case models.UserStatusActive:
+		query = query.Where("(status = ? OR status_expires_at <= ?)", models.UserStatusActive, time.Now())
+	default:
+		query = query.Where("status = ? AND (status_expires_at IS NULL OR status_expires_at > ?)", filter.Status, time.Now())
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+
+	return query
+}
+
+// sortUsers orders by the requested column, the id keeps pages stable
+func sortUsers(query *gorm.DB, filter *models.UserFilter) *gorm.DB {
+	column, ok := models.UserSortColumns[filter.Sort]
+	if !ok {
+		column = "created_at"
+	}
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+
+	return query.Order(column + " " + direction).Order("id")
+}
+
+func (r *UserRepository) GetAllUser(ctx context.Context, filter *models.UserFilter, page *models.PageQuery) (*models.Page, error) {
+	// The filters are shared by the count and the page query
+	query := r.filterUsers(ctx, filter).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
 	users := []*models.User{}
-	res := r.db.Model(&models.User{}).Where("deleted_at IS NULL").Preload("Role").Find(&users)
+	res := sortUsers(query.Preload("Role"), filter).
+		Offset(page.Offset()).
+		Limit(page.Limit).
+		Find(&users)
 	if res.Error != nil {
 		return nil, res.Error
 	}
 
+	return &models.Page{
+		Items: toUserResponses(users),
+		Total: total,
+		Page:  page.Page,
+		Limit: page.Limit,
+	}, nil
+}
+
+// ExportUsers returns every user matching the filter, calls fn for each batch
+// of at most batchSize users so large exports are not held in memory
+func (r *UserRepository) ExportUsers(ctx context.Context, filter *models.UserFilter, batchSize int, fn func(users []*models.UserResponse) error) error {
+	query := sortUsers(r.filterUsers(ctx, filter).Preload("Role"), filter).Session(&gorm.Session{})
+
+	for offset := 0; ; offset += batchSize {
+		users := []*models.User{}
+		if err := query.Offset(offset).Limit(batchSize).Find(&users).Error; err != nil {
+			return err
+		}
+
+		if len(users) > 0 {
+			if err := fn(toUserResponses(users)); err != nil {
+				return err
+			}
+		}
+
+		if len(users) < batchSize {
+			return nil
+		}
+	}
+}
+
+func toUserResponses(users []*models.User) []*models.UserResponse {
 	userResponses := []*models.UserResponse{}
 	for _, user := range users {
 		response := &models.UserResponse{
 			ID: user.ID,
 			Name: user.Name,
 			Email: user.Email,
+			Branch: user.Branch,
 			Role: models.RoleResponse{
 				ID: user.Role.ID,
 				Name: user.Role.Name,
 				Permission: user.Role.Permission,
 			},
+			EmailVerifiedAt: user.EmailVerifiedAt,
+			Status: user.StatusAt(time.Now()),
+			StatusReason: user.StatusReason,
+			StatusExpiresAt: user.StatusExpiresAt,
+			CreatedAt: user.CreatedAt,
 		}
 		userResponses = append(userResponses, response)
 	}
-	return userResponses, nil
+	return userResponses
 }
 
-func (r *UserRepository) CreateUser(ctx context.Context, formData *models.CreateUserForm) error {
+// likeEscaper keeps user input from acting as LIKE wildcards
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
+// CreateUser stores a new user without a password, see InvitationServices
+func (r *UserRepository) CreateUser(ctx context.Context, formData *models.CreateUserForm) (*models.User, error) {
 	ban := formData.Name
 
 	if ban == "administrator" || ban == "admin" || ban == "user" {
-		return fmt.Errorf("Cannot create %v", ban)
+		return nil, fmt.Errorf("Cannot create %v", ban)
 	}
 
 	tx := r.db.Begin()
@@ -55,30 +160,36 @@ func (r *UserRepository) CreateUser(ctx context.Context, formData *models.Create
 	var existingUser models.User
 	if err := tx.Where("email = ?", formData.Email).First(&existingUser).Error; err == nil {
 		tx.Rollback()
-		return errors.New("This email is already used")
+		return nil, errors.New("This email is already used")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		tx.Rollback()
-		return err
-	}
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(formData.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
+		return nil, err
 	}
 
 	newUser := models.User{
-		Name:     formData.Name,
-		Email:    formData.Email,
-		Password: string(hashedPassword),
-		RoleID:   formData.Role,
+		Name:   formData.Name,
+		Email:  formData.Email,
+		RoleID: formData.Role,
+	}
+	if formData.Branch != nil {
+		newUser.Branch = *formData.Branch
 	}
 
 	if err := tx.Create(&newUser).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 
-	return tx.Commit().Error
+	if err := recordRedactedAudit(ctx, tx, models.AuditCreate, models.AuditEntityUser, newUser.ID, nil, &newUser, userPersonalFields...); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return &newUser, nil
 }
 
 
@@ -96,6 +207,12 @@ func (r *UserRepository) UpdateUser(ctx context.Context, updateData map[string]i
 		return errors.New("you cannot change the email")
 	}
 
+	// Protected users keep their role, the setup administrator cannot be demoted
+	if roleId, ok := updateData["role_id"]; ok && roleId != existingUser.RoleID && existingUser.IsProtected {
+		tx.Rollback()
+		return errors.New("Cannot change the role of this user")
+	}
+
 	res := tx.Model(&models.User{}).Where("id = ?", userId).Updates(updateData)
 	if res.Error != nil {
 		tx.Rollback()
@@ -107,9 +224,63 @@ func (r *UserRepository) UpdateUser(ctx context.Context, updateData map[string]i
 		return errors.New("no changes were made to the user")
 	}
 
+	var updatedUser models.User
+	if err := tx.First(&updatedUser, "id = ?", userId).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	changes, err := models.DiffOf(&existingUser, &updatedUser)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	redactAudit(changes, userPersonalFields...)
+
+	if err := writeAudit(ctx, tx, models.AuditUpdate, models.AuditEntityUser, userId, changes); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit().Error
 }
 
+// GetTakenEmails returns which of emails already belong to a user, deleted
+// users included since their address stays reserved until they are purged
+func (r *UserRepository) GetTakenEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	taken := map[string]bool{}
+	if len(emails) == 0 {
+		return taken, nil
+	}
+
+	var existing []string
+	if err := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).Where("email IN ?", emails).Pluck("email", &existing).Error; err != nil {
+		return nil, err
+	}
+
+	for _, email := range existing {
+		taken[strings.ToLower(email)] = true
+	}
+	return taken, nil
+}
+
+// ImportUsers creates all users or none of them, passwords are left empty so
+// the users cannot sign in until they accept an invitation
+func (r *UserRepository) ImportUsers(ctx context.Context, users []*models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, user := range users {
+			if err := tx.Create(user).Error; err != nil {
+				return fmt.Errorf("failed to import %s: %v", user.Email, err)
+			}
+
+			if err := recordRedactedAudit(ctx, tx, models.AuditCreate, models.AuditEntityUser, user.ID, nil, user, userPersonalFields...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *UserRepository) DeleteUser(ctx context.Context, userId uuid.UUID) error {
 	tx := r.db.Begin()
 
@@ -135,5 +306,220 @@ func (r *UserRepository) DeleteUser(ctx context.Context, userId uuid.UUID) error
 		return errors.New("no user was deleted")
 	}
 
+	if err := recordRedactedAudit(ctx, tx, models.AuditDelete, models.AuditEntityUser, userId, &existingUser, nil, userPersonalFields...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit().Error
+}
+
+// GetDeletedUsers returns one page of soft deleted users that were not purged,
+// most recently deleted first
+func (r *UserRepository) GetDeletedUsers(ctx context.Context, page *models.PageQuery) (*models.Page, error) {
+	query := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND purged_at IS NULL AND is_service_account = ?", false).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	users := []*models.User{}
+	res := query.
+		Preload("Role").
+		Order("deleted_at DESC").
+		Order("id").
+		Offset(page.Offset()).
+		Limit(page.Limit).
+		Find(&users)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	userResponses := []*models.DeletedUserResponse{}
+	for _, user := range users {
+		userResponses = append(userResponses, &models.DeletedUserResponse{
+			ID: user.ID,
+			Name: user.Name,
+			Email: user.Email,
+			Branch: user.Branch,
+			Role: models.RoleResponse{
+				ID: user.Role.ID,
+				Name: user.Role.Name,
+				Permission: user.Role.Permission,
+			},
+			CreatedAt: user.CreatedAt,
+			DeletedAt: user.DeletedAt.Time,
+		})
+	}
+
+	return &models.Page{
+		Items: userResponses,
+		Total: total,
+		Page:  page.Page,
+		Limit: page.Limit,
+	}, nil
+}
+
+// findDeletedUser loads a soft deleted user that can still be restored or purged
+func findDeletedUser(tx *gorm.DB, userId uuid.UUID) (*models.User, error) {
+	user := &models.User{}
+	err := tx.Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL AND is_service_account = ?", userId, false).
+		First(user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("deleted user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsProtected {
+		return nil, errors.New("Cannot change this user")
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) RestoreUser(ctx context.Context, userId uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		user, err := findDeletedUser(tx, userId)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", userId).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		changes := map[string]models.AuditChange{
+			"deleted_at": {Before: user.DeletedAt.Time, After: nil},
+		}
+		return writeAudit(ctx, tx, models.AuditRestore, models.AuditEntityUser, userId, changes)
+	})
+}
+
+// PurgeUser permanently removes the personal data of a deleted user. The row
+// itself is kept but anonymised so orders, cars and car types that reference
+// it stay intact, the profile and everything used to sign in are deleted.
+func (r *UserRepository) PurgeUser(ctx context.Context, userId uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := findDeletedUser(tx, userId); err != nil {
+			return err
+		}
+
+		return anonymiseUser(ctx, tx, userId, time.Now())
+	})
+}
+
+// userPersonalFields are redacted in the audit entries of users, the status
+// reason is free text and may name the user too
+var userPersonalFields = []string{"name", "email", "password", "branch", "status_reason"}
+
+// anonymiseUser scrubs the personal data of a deleted user, see PurgeUser
+func anonymiseUser(ctx context.Context, tx *gorm.DB, userId uuid.UUID, now time.Time) error {
+	personal := []interface{}{
+		&models.CustomerProfile{},
+		&models.Session{},
+		&models.UserIdentity{},
+		&models.UserMFA{},
+		&models.MFAChallenge{},
+		&models.PasswordHistory{},
+		&models.APIKey{},
+		&models.UserPermission{},
+		&models.UserInvitation{},
+	}
+	for _, model := range personal {
+		if err := tx.Where("user_id = ?", userId).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	res := tx.Unscoped().Model(&models.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"name":              "Deleted user",
+		"email":             fmt.Sprintf("deleted-%s@deleted.invalid", userId),
+		"password":          "",
+		"branch":            "",
+		"email_verified_at": nil,
+		"purged_at":         now,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	// The old values are personal data, only record that they were removed
+	changes := map[string]models.AuditChange{
+		"purged_at": {Before: nil, After: now},
+	}
+	for _, field := range []string{"name", "email", "password", "branch"} {
+		models.Redact(changes, field)
+	}
+	return writeAudit(ctx, tx, models.AuditPurge, models.AuditEntityUser, userId, changes)
+}
+
+// SetUserStatus suspends, blacklists or reactivates a user. Blacklisting also
+// puts the user's identifiers on the blacklist so a new account is refused,
+// reactivating removes the entries created for the user.
+func (r *UserRepository) SetUserStatus(ctx context.Context, userId uuid.UUID, formData *models.UserStatusForm, setBy uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existingUser models.User
+		if err := tx.First(&existingUser, "id = ?", userId).Error; err != nil {
+			return errors.New("user not found")
+		}
+
+		if existingUser.IsProtected {
+			return errors.New("Cannot change the status of this user")
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":            formData.Status,
+			"status_reason":     formData.Reason,
+			"status_expires_at": formData.ExpiresAt,
+			"status_set_by":     setBy,
+			"status_set_at":     now,
+		}
+		if formData.Status == models.UserStatusActive {
+			updates["status_reason"] = ""
+			updates["status_expires_at"] = nil
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", userId).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userId).Delete(&models.BlacklistEntry{}).Error; err != nil {
+			return err
+		}
+
+		if formData.Status == models.UserStatusBlacklisted {
+			entry := &models.BlacklistEntry{
+				UserID:    &userId,
+				Email:     models.NormalizeEmail(existingUser.Email),
+				Phone:     models.NormalizePhone(formData.Phone),
+				IDNumber:  models.NormalizeIDNumber(formData.IDNumber),
+				Reason:    formData.Reason,
+				ExpiresAt: formData.ExpiresAt,
+				CreatedBy: setBy,
+			}
+			if err := tx.Create(entry).Error; err != nil {
+				return err
+			}
+		}
+
+		var updatedUser models.User
+		if err := tx.First(&updatedUser, "id = ?", userId).Error; err != nil {
+			return err
+		}
+
+		changes, err := models.DiffOf(&existingUser, &updatedUser)
+		if err != nil {
+			return err
+		}
+
+		redactAudit(changes, userPersonalFields...)
+		return writeAudit(ctx, tx, models.AuditStatus, models.AuditEntityUser, userId, changes)
+	})
 }
\ No newline at end of file
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *APIKeyRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	if err := r.db.WithContext(ctx).Where("prefix = ?", prefix).First(key).Error; err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepository) GetAPIKeys(ctx context.Context, userId uuid.UUID) ([]*models.APIKey, error) {
+	keys := []*models.APIKey{}
+	res := r.db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("created_at DESC").
+		Find(&keys)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, userId uuid.UUID, keyId uuid.UUID, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyId, userId).
+		Update("revoked_at", now)
+	return res.RowsAffected, res.Error
+}
+
+func (r *APIKeyRepository) RevokeUserAPIKeys(ctx context.Context, userId uuid.UUID, now time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", now).Error
+}
+
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, keyId uuid.UUID, now time.Time, ip string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ?", keyId).
+		UpdateColumns(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error
+}
+
+func (r *APIKeyRepository) CreateServiceAccount(ctx context.Context, user *models.User) error {
+	tx := r.db.WithContext(ctx).Begin()
+
+	var role models.Role
+	if err := tx.Where("id = ?", user.RoleID).First(&role).Error; err != nil {
+		tx.Rollback()
+		return errors.New("role not found")
+	}
+
+	user.IsServiceAccount = true
+	if err := tx.Create(user).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, models.AuditCreate, models.AuditEntityUser, user.ID, nil, user); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	user.Role = role
+	return nil
+}
+
+func (r *APIKeyRepository) GetServiceAccounts(ctx context.Context) ([]*models.User, error) {
+	users := []*models.User{}
+	res := r.db.WithContext(ctx).
+		Preload("Role").
+		Where("is_service_account = ?", true).
+		Order("created_at DESC").
+		Find(&users)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return users, nil
+}
+
+func (r *APIKeyRepository) GetServiceAccount(ctx context.Context, userId uuid.UUID) (*models.User, error) {
+	user := &models.User{}
+	if err := r.db.WithContext(ctx).Preload("Role").Where("id = ? AND is_service_account = ?", userId, true).First(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteServiceAccount soft deletes the account and revokes all of its keys
+func (r *APIKeyRepository) DeleteServiceAccount(ctx context.Context, userId uuid.UUID) error {
+	tx := r.db.WithContext(ctx).Begin()
+
+	var existing models.User
+	if err := tx.Where("id = ? AND is_service_account = ?", userId, true).First(&existing).Error; err != nil {
+		tx.Rollback()
+		return errors.New("service account not found")
+	}
+
+	if err := tx.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	res := tx.Where("id = ? AND is_service_account = ?", userId, true).Delete(&models.User{})
+	if res.Error != nil {
+		tx.Rollback()
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.New("service account not found")
+	}
+
+	if err := recordAudit(ctx, tx, models.AuditDelete, models.AuditEntityUser, userId, &existing, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func NewAPIKeyRepository(db *gorm.DB) models.APIKeyRepository {
+	return &APIKeyRepository{
+		db: db,
+	}
+}
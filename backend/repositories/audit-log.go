@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+func (r *AuditLogRepository) GetAuditLogs(ctx context.Context, filter *models.AuditLogFilter, page *models.PageQuery) (*models.Page, error) {
+	query := r.db.WithContext(ctx).Model(&models.AuditLog{})
+
+	if filter.ActorID != uuid.Nil {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.ImpersonatorID != uuid.Nil {
+		query = query.Where("impersonator_id = ?", filter.ImpersonatorID)
+	}
+	if filter.Entity != "" {
+		query = query.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID != uuid.Nil {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	// The filters are shared by the count and the page query
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	logs := []*models.AuditLog{}
+	res := query.
+		Order("created_at DESC").
+		Offset(page.Offset()).
+		Limit(page.Limit).
+		Find(&logs)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return &models.Page{
+		Items: logs,
+		Total: total,
+		Page:  page.Page,
+		Limit: page.Limit,
+	}, nil
+}
+
+// recordAudit writes the audit entry of a change in the transaction that
+// makes the change, so the change and its entry are committed together. The
+// actor comes from the request context.
+func recordAudit(ctx context.Context, tx *gorm.DB, action string, entity string, entityId uuid.UUID, before interface{}, after interface{}) error {
+	changes, err := models.DiffOf(before, after)
+	if err != nil {
+		return err
+	}
+
+	return writeAudit(ctx, tx, action, entity, entityId, changes)
+}
+
+// recordRedactedAudit is recordAudit for records holding personal data. The
+// audit log cannot be erased later, so for the given fields it only records
+// that they changed.
+func recordRedactedAudit(ctx context.Context, tx *gorm.DB, action string, entity string, entityId uuid.UUID, before interface{}, after interface{}, fields ...string) error {
+	changes, err := models.DiffOf(before, after)
+	if err != nil {
+		return err
+	}
+
+	redactAudit(changes, fields...)
+	return writeAudit(ctx, tx, action, entity, entityId, changes)
+}
+
+// redactAudit redacts the fields that are part of changes
+func redactAudit(changes map[string]models.AuditChange, fields ...string) {
+	for _, field := range fields {
+		if _, ok := changes[field]; ok {
+			models.Redact(changes, field)
+		}
+	}
+}
+
+func writeAudit(ctx context.Context, tx *gorm.DB, action string, entity string, entityId uuid.UUID, changes map[string]models.AuditChange) error {
+	entry := &models.AuditLog{
+		Action:   action,
+		Entity:   entity,
+		EntityID: entityId,
+		Changes:  changes,
+	}
+
+	if actor, ok := models.AuditActorFrom(ctx); ok {
+		entry.ActorID = &actor.UserID
+		entry.IP = actor.IP
+		if actor.ImpersonatorID != uuid.Nil {
+			entry.ImpersonatorID = &actor.ImpersonatorID
+		}
+	}
+
+	return tx.Create(entry).Error
+}
+
+func NewAuditLogRepository(db *gorm.DB) models.AuditLogRepository {
+	return &AuditLogRepository{
+		db: db,
+	}
+}
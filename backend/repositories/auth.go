@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/DestaAri1/RentAuto/models"
 	"github.com/google/uuid"
@@ -38,6 +40,115 @@ func (r *AuthRepository) RegisterUser(ctx context.Context, registerData *models.
 	return user, nil
 }
 
+// FindOrCreateOIDCUser returns the user linked to the provider subject. An
+// existing account is linked only when the provider verified the email and
+// canLink allows it, otherwise a new user with the default role is created.
+func (r *AuthRepository) FindOrCreateOIDCUser(ctx context.Context, provider string, claims *models.OIDCClaims, canLink func(user *models.User) error) (*models.User, error) {
+	user := &models.User{}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+		if err == nil {
+			return tx.Preload("Role").First(user, "id = ?", identity.UserID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if claims.Email == "" || !claims.EmailVerified {
+			return errors.New("the identity provider did not return a verified email")
+		}
+
+		err = tx.Where("email = ?", claims.Email).First(user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			blacklisted, err := isBlacklisted(tx, claims.Email, "", "", time.Now())
+			if err != nil {
+				return err
+			}
+			if blacklisted {
+				return models.ErrBlacklisted
+			}
+
+			// Get default user role
+			var role models.Role
+			if err := tx.Where("name = ?", "user").First(&role).Error; err != nil {
+				return err
+			}
+
+			name := claims.Name
+			if name == "" {
+				name = claims.Email
+			}
+
+			verifiedAt := time.Now()
+			*user = models.User{
+				Name:   name,
+				Email:  claims.Email,
+				RoleID: role.ID,
+				// The provider verified the email, see the check above
+				EmailVerifiedAt: &verifiedAt,
+			}
+
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else if err := canLink(user); err != nil {
+			return err
+		} else if user.EmailVerifiedAt == nil {
+			if err := tx.Model(user).Update("email_verified_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+
+		identity = models.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}
+		if err := tx.Create(&identity).Error; err != nil {
+			return err
+		}
+
+		return tx.Preload("Role").First(user, "id = ?", user.ID).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// LinkOIDCIdentity links the provider subject to userId, a subject that is
+// already linked to another user is refused
+func (r *AuthRepository) LinkOIDCIdentity(ctx context.Context, userId uuid.UUID, provider string, claims *models.OIDCClaims) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+		if err == nil {
+			if identity.UserID != userId {
+				return models.ErrIdentityLinked
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		identity = models.UserIdentity{
+			UserID:   userId,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}
+		return tx.Create(&identity).Error
+	})
+}
+
 func (r *AuthRepository) GetUser(ctx context.Context, query interface{}, args ...interface{}) (*models.User, error) {
 	user := &models.User{}
 	if err := r.db.Preload("Role").Where(query, args...).First(user).Error; err != nil {
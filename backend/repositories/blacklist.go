@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BlacklistRepository struct {
+	db *gorm.DB
+}
+
+// GetBlacklist returns one page of entries, expired ones included, newest first
+func (r *BlacklistRepository) GetBlacklist(ctx context.Context, page *models.PageQuery) (*models.Page, error) {
+	query := r.db.WithContext(ctx).Model(&models.BlacklistEntry{}).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	entries := []*models.BlacklistEntry{}
+	res := query.
+		Order("created_at DESC").
+		Order("id").
+		Offset(page.Offset()).
+		Limit(page.Limit).
+		Find(&entries)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return &models.Page{
+		Items: entries,
+		Total: total,
+		Page:  page.Page,
+		Limit: page.Limit,
+	}, nil
+}
+
+// blacklistPersonalFields are redacted in the audit entries of the blacklist
+var blacklistPersonalFields = []string{"email", "phone", "id_number", "reason"}
+
+func (r *BlacklistRepository) CreateBlacklistEntry(ctx context.Context, entry *models.BlacklistEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+
+		return recordRedactedAudit(ctx, tx, models.AuditCreate, models.AuditEntityBlacklist, entry.ID, nil, entry, blacklistPersonalFields...)
+	})
+}
+
+func (r *BlacklistRepository) DeleteBlacklistEntry(ctx context.Context, entryId uuid.UUID) (int64, error) {
+	var deleted int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.BlacklistEntry
+		if err := tx.First(&existing, "id = ?", entryId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		res := tx.Delete(&models.BlacklistEntry{}, "id = ?", entryId)
+		if res.Error != nil {
+			return res.Error
+		}
+		deleted = res.RowsAffected
+
+		return recordRedactedAudit(ctx, tx, models.AuditDelete, models.AuditEntityBlacklist, entryId, &existing, nil, blacklistPersonalFields...)
+	})
+
+	return deleted, err
+}
+
+func (r *BlacklistRepository) IsBlacklisted(ctx context.Context, email string, phone string, idNumber string) (bool, error) {
+	return isBlacklisted(r.db.WithContext(ctx), email, phone, idNumber, time.Now())
+}
+
+// isBlacklisted is shared with the registration paths that run inside a transaction
+func isBlacklisted(tx *gorm.DB, email string, phone string, idNumber string, now time.Time) (bool, error) {
+	email = models.NormalizeEmail(email)
+	phone = models.NormalizePhone(phone)
+	idNumber = models.NormalizeIDNumber(idNumber)
+
+	conditions := []string{}
+	args := []interface{}{}
+	if email != "" {
+		conditions = append(conditions, "email = ?")
+		args = append(args, email)
+	}
+	if phone != "" {
+		conditions = append(conditions, "phone = ?")
+		args = append(args, phone)
+	}
+	if idNumber != "" {
+		conditions = append(conditions, "id_number = ?")
+		args = append(args, idNumber)
+	}
+	if len(conditions) == 0 {
+		return false, nil
+	}
+
+	var count int64
+	err := tx.Model(&models.BlacklistEntry{}).
+		Where("("+strings.Join(conditions, " OR ")+")", args...).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func NewBlacklistRepository(db *gorm.DB) models.BlacklistRepository {
+	return &BlacklistRepository{
+		db: db,
+	}
+}
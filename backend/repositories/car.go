@@ -46,6 +46,15 @@ func (r *CarRepository) GetCars(ctx context.Context) ([]*models.CarParentRespons
 	return responses, nil
 }
 
+// GetCar loads a car for policies that check ownership or branch
+func (r *CarRepository) GetCar(ctx context.Context, carId uuid.UUID) (*models.CarParent, error) {
+	car := &models.CarParent{}
+	if err := r.db.WithContext(ctx).First(car, "id = ?", carId).Error; err != nil {
+		return nil, err
+	}
+	return car, nil
+}
+
 func (r *CarRepository) CreateCar(ctx context.Context, formData *models.FormCarParent, userId uuid.UUID) error {
 	newSlug, err := utils.GenerateUniqueSlug(r.db, "car_parents", "slug", formData.Name)
 	if err != nil {
@@ -65,6 +74,12 @@ func (r *CarRepository) CreateCar(ctx context.Context, formData *models.FormCarP
 
 	tx := r.db.Begin()
 
+	// A car belongs to the branch of the user who adds it
+	if err := tx.Model(&models.User{}).Select("branch").Where("id = ?", userId).Scan(&cars.Branch).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	checkType := tx.Model(&models.CarTypes{}).Where("id = ?", formData.TypeId).First(&models.CarTypes{})
 
 	if checkType.RowsAffected == 0 {
@@ -80,6 +95,11 @@ func (r *CarRepository) CreateCar(ctx context.Context, formData *models.FormCarP
 		return res.Error
 	}
 
+	if err := recordAudit(ctx, tx, models.AuditCreate, models.AuditEntityCar, cars.ID.ID, nil, cars); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	tx.Commit()
 	return nil
 }
@@ -143,6 +163,17 @@ func (r *CarRepository) UpdateCar(ctx context.Context, updateData map[string]int
 		return gorm.ErrRecordNotFound
 	}
 
+	var updatedCar models.CarParent
+	if err := tx.First(&updatedCar, "id = ?", carId).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, models.AuditUpdate, models.AuditEntityCar, carId, &currentCar, &updatedCar); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit().Error
 }
 
@@ -165,6 +196,17 @@ func (r *CarRepository) DeleteCar(ctx context.Context, carId uuid.UUID) error {
 			tx.Rollback()
 			return err
 		}
+
+		if err := recordAudit(ctx, tx, models.AuditDelete, models.AuditEntityCarChild, child.ID.ID, child, nil); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	var car models.CarParent
+	if err := tx.First(&car, "id = ?", carId).Error; err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	// Soft delete parent (uncomment jika ingin menghapus parent)
@@ -173,6 +215,11 @@ func (r *CarRepository) DeleteCar(ctx context.Context, carId uuid.UUID) error {
 	    return err
 	}
 
+	if err := recordAudit(ctx, tx, models.AuditDelete, models.AuditEntityCar, carId, &car, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CustomerProfileRepository struct {
+	db *gorm.DB
+}
+
+func (r *CustomerProfileRepository) GetProfile(ctx context.Context, userId uuid.UUID) (*models.CustomerProfile, error) {
+	profile := &models.CustomerProfile{}
+	err := r.db.WithContext(ctx).First(profile, "user_id = ?", userId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.CustomerProfile{UserID: userId}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// SaveProfile creates or replaces the profile, the audit entry names the
+// changed fields without their values. Registration only checks the blacklist
+// for what was entered there, a phone or licence number added later is
+// checked here. Unchanged values are not checked again so a profile stays
+// editable.
+func (r *CustomerProfileRepository) SaveProfile(ctx context.Context, profile *models.CustomerProfile) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing *models.CustomerProfile
+		var before models.CustomerProfile
+		err := tx.First(&before, "user_id = ?", profile.UserID).Error
+		switch {
+		case err == nil:
+			existing = &before
+			profile.CreatedAt = before.CreatedAt
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		phone, licence := profile.Phone, profile.LicenceNumber
+		if existing != nil {
+			if models.NormalizePhone(phone) == models.NormalizePhone(existing.Phone) {
+				phone = ""
+			}
+			if models.NormalizeIDNumber(licence) == models.NormalizeIDNumber(existing.LicenceNumber) {
+				licence = ""
+			}
+		}
+		blacklisted, err := isBlacklisted(tx, "", phone, licence, time.Now())
+		if err != nil {
+			return err
+		}
+		if blacklisted {
+			return models.ErrProfileBlacklisted
+		}
+
+		if err := tx.Save(profile).Error; err != nil {
+			return err
+		}
+
+		action := models.AuditUpdate
+		var previous interface{} = existing
+		if existing == nil {
+			action = models.AuditCreate
+			previous = nil
+		}
+
+		changes, err := models.DiffOf(previous, profile)
+		if err != nil {
+			return err
+		}
+
+		// The audit log cannot be erased later, so it only records that
+		// personal data changed
+		for field := range changes {
+			if field != "user_id" {
+				models.Redact(changes, field)
+			}
+		}
+
+		return writeAudit(ctx, tx, action, models.AuditEntityProfile, profile.UserID, changes)
+	})
+}
+
+func NewCustomerProfileRepository(db *gorm.DB) models.CustomerProfileRepository {
+	return &CustomerProfileRepository{
+		db: db,
+	}
+}
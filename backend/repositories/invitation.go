@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+func (r *InvitationRepository) CreateInvitation(ctx context.Context, invitation *models.UserInvitation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.UserInvitation{}).
+			Where("user_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitation.UserID).
+			Update("revoked_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+
+		return tx.Create(invitation).Error
+	})
+}
+
+func (r *InvitationRepository) GetInvitationByHash(ctx context.Context, hash string) (*models.UserInvitation, error) {
+	invitation := &models.UserInvitation{}
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(invitation).Error; err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// GetPendingInvitations lists open invitations of users that still exist,
+// newest first
+func (r *InvitationRepository) GetPendingInvitations(ctx context.Context, page *models.PageQuery) (*models.Page, error) {
+	query := r.db.WithContext(ctx).
+		Table("user_invitations").
+		Joins("JOIN users ON users.id = user_invitations.user_id AND users.deleted_at IS NULL").
+		Where("user_invitations.accepted_at IS NULL AND user_invitations.revoked_at IS NULL").
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	invitations := []*models.PendingInvitation{}
+	res := query.
+		Select("user_invitations.id, user_invitations.user_id, users.name, users.email, user_invitations.created_by, user_invitations.expires_at, user_invitations.reset, user_invitations.created_at").
+		Order("user_invitations.created_at DESC").
+		Order("user_invitations.id").
+		Offset(page.Offset()).
+		Limit(page.Limit).
+		Scan(&invitations)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	now := time.Now()
+	for _, invitation := range invitations {
+		invitation.Expired = now.After(invitation.ExpiresAt)
+	}
+
+	return &models.Page{
+		Items: invitations,
+		Total: total,
+		Page:  page.Page,
+		Limit: page.Limit,
+	}, nil
+}
+
+func (r *InvitationRepository) GetPendingInvitation(ctx context.Context, invitationId uuid.UUID) (*models.UserInvitation, error) {
+	invitation := &models.UserInvitation{}
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitationId).
+		First(invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrInvitationNotPending
+	}
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (r *InvitationRepository) RevokeInvitation(ctx context.Context, invitationId uuid.UUID, now time.Time) error {
+	res := r.db.WithContext(ctx).
+		Model(&models.UserInvitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitationId).
+		Update("revoked_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return models.ErrInvitationNotPending
+	}
+	return nil
+}
+
+// AcceptInvitation only succeeds for the first caller, opening the link proves
+// the user owns the address so the email becomes verified as well
+func (r *InvitationRepository) AcceptInvitation(ctx context.Context, invitationId uuid.UUID, now time.Time) (bool, error) {
+	accepted := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		invitation := &models.UserInvitation{}
+		res := tx.Model(invitation).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitationId).
+			Update("accepted_at", now)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+
+		if err := tx.First(invitation, "id = ?", invitationId).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", invitation.UserID).
+			Update("email_verified_at", now).Error; err != nil {
+			return err
+		}
+
+		accepted = true
+		return nil
+	})
+
+	return accepted, err
+}
+
+func NewInvitationRepository(db *gorm.DB) models.InvitationRepository {
+	return &InvitationRepository{
+		db: db,
+	}
+}
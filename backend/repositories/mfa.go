@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MFARepository stores the TOTP secrets encrypted with secretKey, callers
+// only ever see them in plaintext
+type MFARepository struct {
+	db        *gorm.DB
+	secretKey []byte
+}
+
+func (r *MFARepository) GetMFA(ctx context.Context, userId uuid.UUID) (*models.UserMFA, error) {
+	mfa := &models.UserMFA{}
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userId).First(mfa).Error; err != nil {
+		return nil, err
+	}
+
+	// Rows written before the secrets were encrypted are read as they are
+	if utils.IsSealedSecret(mfa.Secret) {
+		secret, err := utils.OpenSecret(r.secretKey, mfa.Secret)
+		if err != nil {
+			return nil, err
+		}
+		mfa.Secret = secret
+	}
+	return mfa, nil
+}
+
+func (r *MFARepository) SaveMFA(ctx context.Context, mfa *models.UserMFA) error {
+	secret, err := utils.SealSecret(r.secretKey, mfa.Secret)
+	if err != nil {
+		return err
+	}
+
+	// Saved from a copy so the caller keeps the plaintext secret
+	stored := *mfa
+	stored.Secret = secret
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&stored).Error
+}
+
+// SealPlaintextSecrets encrypts the secrets stored before encryption was
+// introduced
+func (r *MFARepository) SealPlaintextSecrets(ctx context.Context) (int, error) {
+	var rows []models.UserMFA
+	if err := r.db.WithContext(ctx).Select("user_id", "secret").Where("secret NOT LIKE ?", "enc:%").Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		secret, err := utils.SealSecret(r.secretKey, row.Secret)
+		if err != nil {
+			return 0, err
+		}
+		// Guarded by the old value in case the row changed meanwhile
+		err = r.db.WithContext(ctx).
+			Model(&models.UserMFA{}).
+			Where("user_id = ? AND secret = ?", row.UserID, row.Secret).
+			UpdateColumn("secret", secret).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
+
+func (r *MFARepository) DeleteMFA(ctx context.Context, userId uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userId).Delete(&models.UserMFA{}).Error
+}
+
+func (r *MFARepository) CreateChallenge(ctx context.Context, challenge *models.MFAChallenge, window time.Duration) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND expires_at < ?", challenge.UserID, time.Now().Add(-window)).Delete(&models.MFAChallenge{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(challenge).Error
+	})
+}
+
+func (r *MFARepository) GetChallenge(ctx context.Context, challengeId uuid.UUID) (*models.MFAChallenge, error) {
+	challenge := &models.MFAChallenge{}
+	if err := r.db.WithContext(ctx).Where("id = ?", challengeId).First(challenge).Error; err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+func (r *MFARepository) ReserveChallengeAttempt(ctx context.Context, challengeId uuid.UUID, maxAttempts int, now time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ? AND attempts < ?", challengeId, now, maxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *MFARepository) UseChallenge(ctx context.Context, challengeId uuid.UUID, now time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", challengeId).
+		UpdateColumn("used_at", now)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// CountChallengeFailures relies on a used challenge having exactly one
+// successful attempt, every other attempt was an invalid code
+func (r *MFARepository) CountChallengeFailures(ctx context.Context, userId uuid.UUID, since time.Time) (int64, error) {
+	var failures int64
+	err := r.db.WithContext(ctx).
+		Model(&models.MFAChallenge{}).
+		Select("COALESCE(SUM(attempts), 0) - COUNT(used_at)").
+		Where("user_id = ? AND created_at >= ?", userId, since).
+		Scan(&failures).Error
+	return failures, err
+}
+
+func NewMFARepository(db *gorm.DB, secretKey []byte) models.MFARepository {
+	return &MFARepository{
+		db:        db,
+		secretKey: secretKey,
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PasswordRepository struct {
+	db *gorm.DB
+}
+
+func (r *PasswordRepository) GetPasswordHistory(ctx context.Context, userId uuid.UUID, limit int) ([]*models.PasswordHistory, error) {
+	history := []*models.PasswordHistory{}
+	res := r.db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&history)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return history, nil
+}
+
+// AddPasswordHistory stores the hash and trims the history to the newest keep entries
+func (r *PasswordRepository) AddPasswordHistory(ctx context.Context, userId uuid.UUID, hash string, keep int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.PasswordHistory{UserID: userId, Hash: hash}).Error; err != nil {
+			return err
+		}
+
+		var keepIds []uuid.UUID
+		if err := tx.Model(&models.PasswordHistory{}).
+			Where("user_id = ?", userId).
+			Order("created_at DESC").
+			Limit(keep).
+			Pluck("id", &keepIds).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ? AND id NOT IN ?", userId, keepIds).Delete(&models.PasswordHistory{}).Error
+	})
+}
+
+func (r *PasswordRepository) UpdatePasswordHash(ctx context.Context, userId uuid.UUID, hash string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", userId).
+		UpdateColumn("password", hash).Error
+}
+
+func NewPasswordRepository(db *gorm.DB) models.PasswordRepository {
+	return &PasswordRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PersonalDataRepository struct {
+	db *gorm.DB
+}
+
+func (r *PersonalDataRepository) GetPersonalData(ctx context.Context, userId uuid.UUID) (*models.PersonalData, error) {
+	db := r.db.WithContext(ctx)
+
+	data := &models.PersonalData{
+		ExportedAt:      time.Now(),
+		User:            &models.User{},
+		Profile:         &models.CustomerProfile{UserID: userId},
+		Orders:          []*models.PersonalDataOrder{},
+		Sessions:        []*models.Session{},
+		Identities:      []*models.UserIdentity{},
+		APIKeys:         []*models.APIKey{},
+		Permissions:     []*models.UserPermission{},
+		ErasureRequests: []*models.ErasureRequest{},
+		AuditLogs:       []*models.AuditLog{},
+	}
+
+	if err := db.Preload("Role").First(data.User, "id = ?", userId).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	err := db.First(data.Profile, "user_id = ?", userId).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Orders are not migrated on every installation
+	if db.Migrator().HasTable(&models.Order{}) {
+		orders := []*models.Order{}
+		if err := db.Preload("Car").Where("user_id = ?", userId).Order("created_at").Find(&orders).Error; err != nil {
+			return nil, err
+		}
+		for _, order := range orders {
+			data.Orders = append(data.Orders, &models.PersonalDataOrder{
+				ID:            order.Id,
+				Car:           order.Car.Name,
+				Information:   order.Information,
+				IsActive:      isSet(order.IsActive),
+				IsPickedUp:    isSet(order.IsPickedUp),
+				PaymentStatus: isSet(order.PayStatus),
+				CreatedAt:     order.CreatedAt,
+			})
+		}
+	}
+
+	lists := []interface{}{
+		&data.Sessions,
+		&data.Identities,
+		&data.APIKeys,
+		&data.Permissions,
+		&data.ErasureRequests,
+	}
+	for _, list := range lists {
+		if err := db.Where("user_id = ?", userId).Order("created_at").Find(list).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	res := db.
+		Where("entity IN ? AND entity_id = ?", []string{models.AuditEntityUser, models.AuditEntityProfile}, userId).
+		Order("created_at").
+		Find(&data.AuditLogs)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return data, nil
+}
+
+func isSet(value *bool) bool {
+	return value != nil && *value
+}
+
+func (r *PersonalDataRepository) GetErasureRequest(ctx context.Context, userId uuid.UUID) (*models.ErasureRequest, error) {
+	request := &models.ErasureRequest{}
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("created_at DESC").First(request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (r *PersonalDataRepository) CreateErasureRequest(ctx context.Context, request *models.ErasureRequest) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, "id = ?", request.UserID).Error; err != nil {
+			return errors.New("user not found")
+		}
+		if user.IsProtected || user.IsServiceAccount {
+			return errors.New("This account cannot be erased")
+		}
+
+		var pending int64
+		if err := tx.Model(&models.ErasureRequest{}).Where("user_id = ? AND status = ?", request.UserID, models.ErasurePending).Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return models.ErrErasurePending
+		}
+
+		request.Status = models.ErasurePending
+		if err := tx.Create(request).Error; err != nil {
+			return err
+		}
+
+		changes, err := models.DiffOf(nil, request)
+		if err != nil {
+			return err
+		}
+		// The reason is free text the audit log could never erase
+		if _, ok := changes["reason"]; ok {
+			models.Redact(changes, "reason")
+		}
+		return writeAudit(ctx, tx, models.AuditCreate, models.AuditEntityErasure, request.ID, changes)
+	})
+}
+
+func (r *PersonalDataRepository) CancelErasureRequest(ctx context.Context, userId uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		request := &models.ErasureRequest{}
+		err := tx.Where("user_id = ? AND status = ?", userId, models.ErasurePending).First(request).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ErrErasureNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		return updateErasureRequest(ctx, tx, request, map[string]interface{}{
+			"status": models.ErasureCancelled,
+		})
+	})
+}
+
+func (r *PersonalDataRepository) GetErasureRequests(ctx context.Context, status string, page *models.PageQuery) (*models.Page, error) {
+	query := r.db.WithContext(ctx).Model(&models.ErasureRequest{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	requests := []*models.ErasureRequest{}
+	res := query.
+		Order("created_at").
+		Order("id").
+		Offset(page.Offset()).
+		Limit(page.Limit).
+		Find(&requests)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return &models.Page{
+		Items: requests,
+		Total: total,
+		Page:  page.Page,
+		Limit: page.Limit,
+	}, nil
+}
+
+// ApproveErasureRequest soft deletes the user and scrubs the account like
+// PurgeUser. Orders stay for the accounts and blacklist entries stay to keep
+// refusing the person, both now point at the anonymised row.
+func (r *PersonalDataRepository) ApproveErasureRequest(ctx context.Context, requestId uuid.UUID, reviewedBy uuid.UUID, note string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		request, err := findPendingErasure(tx, requestId)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.Unscoped().Where("id = ? AND purged_at IS NULL", request.UserID).First(&user).Error; err != nil {
+			return errors.New("user not found")
+		}
+		if user.IsProtected {
+			return errors.New("Cannot change this user")
+		}
+
+		now := time.Now()
+		if !user.DeletedAt.Valid {
+			if err := tx.Delete(&models.User{}, "id = ?", user.ID).Error; err != nil {
+				return err
+			}
+			// Unlike DeleteUser the old values are not recorded, they are
+			// about to be erased
+			if err := writeAudit(ctx, tx, models.AuditDelete, models.AuditEntityUser, user.ID, nil); err != nil {
+				return err
+			}
+		}
+
+		if err := anonymiseUser(ctx, tx, user.ID, now); err != nil {
+			return err
+		}
+
+		return updateErasureRequest(ctx, tx, request, map[string]interface{}{
+			"status":      models.ErasureApproved,
+			"reviewed_by": reviewedBy,
+			"review_note": note,
+			"reviewed_at": now,
+		})
+	})
+}
+
+func (r *PersonalDataRepository) RejectErasureRequest(ctx context.Context, requestId uuid.UUID, reviewedBy uuid.UUID, note string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		request, err := findPendingErasure(tx, requestId)
+		if err != nil {
+			return err
+		}
+
+		return updateErasureRequest(ctx, tx, request, map[string]interface{}{
+			"status":      models.ErasureRejected,
+			"reviewed_by": reviewedBy,
+			"review_note": note,
+			"reviewed_at": time.Now(),
+		})
+	})
+}
+
+func findPendingErasure(tx *gorm.DB, requestId uuid.UUID) (*models.ErasureRequest, error) {
+	request := &models.ErasureRequest{}
+	err := tx.Where("id = ? AND status = ?", requestId, models.ErasurePending).First(request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrErasureNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func updateErasureRequest(ctx context.Context, tx *gorm.DB, request *models.ErasureRequest, updates map[string]interface{}) error {
+	before := *request
+	if err := tx.Model(&models.ErasureRequest{}).Where("id = ?", request.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	if err := tx.First(request, "id = ?", request.ID).Error; err != nil {
+		return err
+	}
+
+	return recordAudit(ctx, tx, models.AuditStatus, models.AuditEntityErasure, request.ID, &before, request)
+}
+
+func NewPersonalDataRepository(db *gorm.DB) models.PersonalDataRepository {
+	return &PersonalDataRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// missReloadInterval limits reloads caused by role IDs missing from the cache,
+// which happens when another instance created the role
+const missReloadInterval = 5 * time.Second
+
+// CachedRoleRepository keeps every role in memory so permission checks need no
+// queries. Writes through it invalidate the cache, ttl bounds how long changes
+// made by other instances stay invisible. A ttl of zero never expires.
+type CachedRoleRepository struct {
+	models.RoleRepository
+	ttl time.Duration
+
+	mu       sync.RWMutex
+	roles    map[uuid.UUID]*models.RoleResponse
+	list     []*models.RoleResponse
+	loadedAt time.Time
+	// generation changes on every invalidation so a slow reload cannot
+	// store roles read before a write
+	generation uint64
+}
+
+func (r *CachedRoleRepository) GetRoles(ctx context.Context) ([]*models.RoleResponse, error) {
+	if list, ok := r.cached(); ok {
+		return list, nil
+	}
+
+	list, _, err := r.reload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]*models.RoleResponse(nil), list...), nil
+}
+
+func (r *CachedRoleRepository) GetRole(ctx context.Context, roleId uuid.UUID) (*models.RoleResponse, error) {
+	r.mu.RLock()
+	role, found := r.roles[roleId]
+	fresh := r.isFresh()
+	recent := time.Since(r.loadedAt) < missReloadInterval
+	r.mu.RUnlock()
+
+	if found && fresh {
+		return role, nil
+	}
+
+	if !found && fresh && recent {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	_, roles, err := r.reload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	role, found = roles[roleId]
+	if !found {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return role, nil
+}
+
+func (r *CachedRoleRepository) CreateRole(ctx context.Context, formData *models.FormRole) error {
+	defer r.Invalidate()
+	return r.RoleRepository.CreateRole(ctx, formData)
+}
+
+func (r *CachedRoleRepository) UpdateRole(ctx context.Context, formData *models.FormRole, roleId uuid.UUID) error {
+	defer r.Invalidate()
+	return r.RoleRepository.UpdateRole(ctx, formData, roleId)
+}
+
+func (r *CachedRoleRepository) DeleteRole(ctx context.Context, roleId uuid.UUID) error {
+	defer r.Invalidate()
+	return r.RoleRepository.DeleteRole(ctx, roleId)
+}
+
+// Invalidate drops the cached roles so the next lookup reloads them
+func (r *CachedRoleRepository) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.roles = nil
+	r.list = nil
+	r.loadedAt = time.Time{}
+	r.generation++
+}
+
+func (r *CachedRoleRepository) cached() ([]*models.RoleResponse, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.isFresh() {
+		return nil, false
+	}
+
+	list := make([]*models.RoleResponse, len(r.list))
+	copy(list, r.list)
+	return list, true
+}
+
+// isFresh must be called with the lock held
+func (r *CachedRoleRepository) isFresh() bool {
+	if r.roles == nil {
+		return false
+	}
+	return r.ttl <= 0 || time.Since(r.loadedAt) < r.ttl
+}
+
+func (r *CachedRoleRepository) reload(ctx context.Context) ([]*models.RoleResponse, map[uuid.UUID]*models.RoleResponse, error) {
+	r.mu.RLock()
+	generation := r.generation
+	r.mu.RUnlock()
+
+	list, err := r.RoleRepository.GetRoles(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	roles := make(map[uuid.UUID]*models.RoleResponse, len(list))
+	for _, role := range list {
+		roles[role.ID] = role
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		// Invalidated meanwhile, the next lookup reloads again
+		return list, roles, nil
+	}
+
+	r.roles = roles
+	r.list = list
+	r.loadedAt = time.Now()
+
+	return list, roles, nil
+}
+
+func NewCachedRoleRepository(repository models.RoleRepository, ttl time.Duration) *CachedRoleRepository {
+	return &CachedRoleRepository{
+		RoleRepository: repository,
+		ttl:            ttl,
+	}
+}
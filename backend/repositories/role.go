@@ -30,6 +30,8 @@ func (r *RoleRepository) GetRoles(ctx context.Context) ([]*models.RoleResponse,
 			ID: role.ID,
 			Name: role.Name,
 			Permission: role.Permission,
+			Parents: role.Parents,
+			IsSystem: role.IsSystem,
 		}
 		roleResponses = append(roleResponses, response)
 	}
@@ -37,6 +39,21 @@ func (r *RoleRepository) GetRoles(ctx context.Context) ([]*models.RoleResponse,
 	return roleResponses, nil
 }
 
+func (r *RoleRepository) GetRole(ctx context.Context, roleId uuid.UUID) (*models.RoleResponse, error) {
+	role := &models.Role{}
+	if err := r.db.WithContext(ctx).Where("id = ?", roleId).First(role).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.RoleResponse{
+		ID: role.ID,
+		Name: role.Name,
+		Permission: role.Permission,
+		Parents: role.Parents,
+		IsSystem: role.IsSystem,
+	}, nil
+}
+
 func (r *RoleRepository) CreateRole(ctx context.Context, formData *models.FormRole) error {
 	data := strings.ToLower(formData.Name)
 	if data == "administrator" || data == "admin" || data == "user" {
@@ -46,14 +63,25 @@ func (r *RoleRepository) CreateRole(ctx context.Context, formData *models.FormRo
 	role := &models.Role{
 		Name:       strings.ToLower(formData.Name),
 		Permission: formData.Permission,
+		Parents:    formData.Parents,
 	}
 
 	tx := r.db.Begin()
+	if err := checkRoleParents(tx, uuid.Nil, formData.Parents); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if res := tx.Create(role); res.Error != nil {
 		tx.Rollback()
 		return res.Error
 	}
 
+	if err := recordAudit(ctx, tx, models.AuditCreate, models.AuditEntityRole, role.ID, nil, role); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	tx.Commit()
 	return nil
 }
@@ -68,9 +96,33 @@ func (r *RoleRepository) UpdateRole(ctx context.Context, formData *models.FormRo
 		return err
 	}
 
+	if err := checkRoleParents(tx, roleId, formData.Parents); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var currentRole models.Role
+	if err := tx.First(&currentRole, "id = ?", roleId).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// The seeded roles are looked up by name, renaming one would break that
+	if currentRole.IsSystem && strings.ToLower(formData.Name) != currentRole.Name {
+		tx.Rollback()
+		return errors.New("System roles cannot be renamed")
+	}
+
+	parentsJSON, err := json.Marshal(formData.Parents)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	updates := map[string]interface{}{
 		"name":       strings.ToLower(formData.Name),
 		"permission": string(permissionJSON), // simpan sebagai string JSON
+		"parents":    string(parentsJSON),
 	}
 
 	if res := tx.Model(&models.Role{}).Where("id = ?", roleId).Updates(updates); res.Error != nil {
@@ -78,6 +130,17 @@ func (r *RoleRepository) UpdateRole(ctx context.Context, formData *models.FormRo
 		return res.Error
 	}
 
+	var updatedRole models.Role
+	if err := tx.First(&updatedRole, "id = ?", roleId).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, models.AuditUpdate, models.AuditEntityRole, roleId, &currentRole, &updatedRole); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	tx.Commit()
 	return nil
 }
@@ -93,9 +156,42 @@ func (r *RoleRepository) DeleteRole(ctx context.Context, roleId uuid.UUID) error
 		return res.Error
 	}
 
-	if checkRole.Name == "Administrator" || checkRole.Name == "User" {
+	if checkRole.IsSystem {
+		tx.Rollback()
+		return errors.New("System roles cannot be deleted")
+	}
+
+	// Deleted users count until they are purged, RestoreUser would bring
+	// them back with a role that no longer exists
+	var assigned int64
+	if err := tx.Unscoped().Model(&models.User{}).Where("role_id = ? AND purged_at IS NULL", roleId).Count(&assigned).Error; err != nil {
 		tx.Rollback()
-		return errors.New("Immune role")
+		return err
+	}
+
+	if assigned > 0 {
+		tx.Rollback()
+		return fmt.Errorf("role is still assigned to %d users, deleted users that can be restored included", assigned)
+	}
+
+	roles := []*models.Role{}
+	if err := tx.Find(&roles).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var children []string
+	for _, role := range roles {
+		for _, parent := range role.Parents {
+			if parent == roleId {
+				children = append(children, role.Name)
+			}
+		}
+	}
+
+	if len(children) > 0 {
+		tx.Rollback()
+		return fmt.Errorf("role is inherited by %s", strings.Join(children, ", "))
 	}
 
 	if res := tx.Where("id = ?", roleId).Delete(&models.Role{}); res.Error != nil {
@@ -103,10 +199,70 @@ func (r *RoleRepository) DeleteRole(ctx context.Context, roleId uuid.UUID) error
 		return res.Error
 	}
 
+	if err := recordAudit(ctx, tx, models.AuditDelete, models.AuditEntityRole, roleId, &checkRole, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	tx.Commit()
 	return nil
 }
 
+// checkRoleParents makes sure every parent exists and that inheriting from
+// them would not lead back to roleId. roleId is uuid.Nil for a new role.
+func checkRoleParents(tx *gorm.DB, roleId uuid.UUID, parents []uuid.UUID) error {
+	if len(parents) == 0 {
+		return nil
+	}
+
+	roles := []*models.Role{}
+	if err := tx.Find(&roles).Error; err != nil {
+		return err
+	}
+
+	parentsOf := make(map[uuid.UUID][]uuid.UUID, len(roles))
+	for _, role := range roles {
+		parentsOf[role.ID] = role.Parents
+	}
+
+	return validateRoleParents(roleId, parents, parentsOf)
+}
+
+// validateRoleParents checks parents against the parents of every existing
+// role, keyed by role ID
+func validateRoleParents(roleId uuid.UUID, parents []uuid.UUID, parentsOf map[uuid.UUID][]uuid.UUID) error {
+	for _, parent := range parents {
+		if parent == roleId {
+			return errors.New("a role cannot inherit from itself")
+		}
+		if _, ok := parentsOf[parent]; !ok {
+			return fmt.Errorf("parent role %s not found", parent)
+		}
+	}
+
+	if roleId == uuid.Nil {
+		return nil
+	}
+
+	// Walk up from the new parents, reaching roleId means a cycle
+	visited := map[uuid.UUID]bool{}
+	pending := append([]uuid.UUID{}, parents...)
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if current == roleId {
+			return errors.New("role inheritance would create a cycle")
+		}
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		pending = append(pending, parentsOf[current]...)
+	}
+
+	return nil
+}
 
 func NewRoleRepository(db *gorm.DB) models.RoleRepository {
 	return &RoleRepository{
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateRoleParents(t *testing.T) {
+	admin, manager, staff, other := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	// staff inherits from manager, manager from admin
+	parentsOf := map[uuid.UUID][]uuid.UUID{
+		admin:   nil,
+		manager: {admin},
+		staff:   {manager},
+		other:   nil,
+	}
+
+	tests := []struct {
+		name    string
+		roleId  uuid.UUID
+		parents []uuid.UUID
+		wantErr string
+	}{
+		{name: "new role", roleId: uuid.Nil, parents: []uuid.UUID{staff}},
+		{name: "new role with unknown parent", roleId: uuid.Nil, parents: []uuid.UUID{uuid.New()}, wantErr: "not found"},
+		{name: "unrelated parent", roleId: other, parents: []uuid.UUID{staff}},
+		{name: "existing chain", roleId: staff, parents: []uuid.UUID{manager, admin}},
+		{name: "itself", roleId: manager, parents: []uuid.UUID{manager}, wantErr: "cannot inherit from itself"},
+		{name: "direct cycle", roleId: manager, parents: []uuid.UUID{staff}, wantErr: "cycle"},
+		{name: "indirect cycle", roleId: admin, parents: []uuid.UUID{staff}, wantErr: "cycle"},
+		{name: "cycle behind a valid parent", roleId: admin, parents: []uuid.UUID{other, staff}, wantErr: "cycle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRoleParents(tt.roleId, tt.parents, parentsOf)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRoleParentsExistingCycle(t *testing.T) {
+	// A cycle already stored must not make the walk loop forever
+	a, b, role := uuid.New(), uuid.New(), uuid.New()
+	parentsOf := map[uuid.UUID][]uuid.UUID{
+		a:    {b},
+		b:    {a},
+		role: nil,
+	}
+
+	if err := validateRoleParents(role, []uuid.UUID{a}, parentsOf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
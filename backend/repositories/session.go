@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// CreateSession stores the session, starting an impersonation is also
+// recorded in the audit log
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	if session.ImpersonatorID == nil {
+		return r.db.WithContext(ctx).Create(session).Error
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+
+		changes := map[string]models.AuditChange{
+			"session_id": {After: session.ID},
+			"expires_at": {After: session.ExpiresAt},
+		}
+		return writeAudit(ctx, tx, models.AuditImpersonate, models.AuditEntityUser, session.UserID, changes)
+	})
+}
+
+func (r *SessionRepository) GetSession(ctx context.Context, sessionId uuid.UUID) (*models.Session, error) {
+	session := &models.Session{}
+	if err := r.db.WithContext(ctx).Where("id = ?", sessionId).First(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *SessionRepository) GetActiveSessions(ctx context.Context, userId uuid.UUID, now time.Time) ([]*models.Session, error) {
+	sessions := []*models.Session{}
+	res := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, now).
+		Order("last_seen_at DESC").
+		Find(&sessions)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return sessions, nil
+}
+
+func (r *SessionRepository) TouchSession(ctx context.Context, sessionId uuid.UUID, now time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ?", sessionId).
+		UpdateColumn("last_seen_at", now).Error
+}
+
+func (r *SessionRepository) RevokeSession(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionId, userId).
+		Update("revoked_at", now)
+	return res.RowsAffected, res.Error
+}
+
+// RevokeUserSessions revokes every active session of the user except exceptId,
+// pass uuid.Nil to revoke all of them
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, userId uuid.UUID, exceptId uuid.UUID, now time.Time) (int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId)
+
+	if exceptId != uuid.Nil {
+		query = query.Where("id <> ?", exceptId)
+	}
+
+	res := query.Update("revoked_at", now)
+	return res.RowsAffected, res.Error
+}
+
+func (r *SessionRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at <= ? OR revoked_at <= ?", before, before).
+		Delete(&models.Session{}).Error
+}
+
+func NewSessionRepository(db *gorm.DB) models.SessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
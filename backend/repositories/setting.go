@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SettingRepository struct {
+	db *gorm.DB
+}
+
+func (r *SettingRepository) GetSettings(ctx context.Context) ([]*models.Setting, error) {
+	settings := []*models.Setting{}
+	if err := r.db.WithContext(ctx).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SaveSetting stores the value and records the change in the audit log,
+// keyed by the setting since settings have no ID. previous is the value that
+// applied before, the default when the setting was never changed.
+func (r *SettingRepository) SaveSetting(ctx context.Context, setting *models.Setting, previous interface{}) error {
+	var value interface{}
+	if err := json.Unmarshal([]byte(setting.Value), &value); err != nil {
+		return err
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(setting).Error; err != nil {
+			return err
+		}
+
+		changes := map[string]models.AuditChange{
+			setting.Key: {Before: previous, After: value},
+		}
+		return writeAudit(ctx, tx, models.AuditUpdate, models.AuditEntitySetting, uuid.Nil, changes)
+	})
+}
+
+func NewSettingRepository(db *gorm.DB) models.SettingRepository {
+	return &SettingRepository{
+		db: db,
+	}
+}
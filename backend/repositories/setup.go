@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"gorm.io/gorm"
+)
+
+type SetupRepository struct {
+	db *gorm.DB
+}
+
+func (r *SetupRepository) IsSetupRequired(ctx context.Context) (bool, error) {
+	return isSetupRequired(r.db.WithContext(ctx))
+}
+
+func isSetupRequired(tx *gorm.DB) (bool, error) {
+	var completed int64
+	if err := tx.Model(&models.SetupState{}).Count(&completed).Error; err != nil {
+		return false, err
+	}
+	if completed > 0 {
+		return false, nil
+	}
+
+	// Soft deleted administrators count, they can still be restored
+	var admins int64
+	err := tx.Unscoped().
+		Model(&models.User{}).
+		Joins("JOIN roles ON roles.id = users.role_id").
+		Where("roles.name = ? AND users.purged_at IS NULL", models.SetupRoleName).
+		Count(&admins).Error
+	if err != nil {
+		return false, err
+	}
+
+	return admins == 0, nil
+}
+
+func (r *SetupRepository) CompleteSetup(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		required, err := isSetupRequired(tx)
+		if err != nil {
+			return err
+		}
+		if !required {
+			return models.ErrSetupCompleted
+		}
+
+		var role models.Role
+		if err := tx.First(&role, "name = ?", models.SetupRoleName).Error; err != nil {
+			return errors.New("the super administrator role is missing")
+		}
+
+		var existingUser models.User
+		if err := tx.Unscoped().Where("email = ?", user.Email).First(&existingUser).Error; err == nil {
+			return errors.New("This email is already used")
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		now := time.Now()
+		user.RoleID = role.ID
+		user.IsProtected = true
+		user.EmailVerifiedAt = &now
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		state := &models.SetupState{
+			ID:          models.SetupStateID,
+			CompletedBy: user.ID,
+			CompletedAt: now,
+		}
+		if err := tx.Create(state).Error; err != nil {
+			return models.ErrSetupCompleted
+		}
+
+		return recordRedactedAudit(ctx, tx, models.AuditCreate, models.AuditEntityUser, user.ID, nil, user, userPersonalFields...)
+	})
+}
+
+func NewSetupRepository(db *gorm.DB) models.SetupRepository {
+	return &SetupRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"gorm.io/gorm"
+)
+
+type SigningKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *SigningKeyRepository) GetSigningKeys(ctx context.Context, now time.Time) ([]*models.SigningKey, error) {
+	keys := []*models.SigningKey{}
+	res := r.db.WithContext(ctx).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("created_at ASC").
+		Find(&keys)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return keys, nil
+}
+
+func (r *SigningKeyRepository) CreateSigningKey(ctx context.Context, key *models.SigningKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *SigningKeyRepository) RetireSigningKey(ctx context.Context, kid string, retiredAt time.Time, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.SigningKey{}).
+		Where("kid = ? AND retired_at IS NULL", kid).
+		Updates(map[string]interface{}{
+			"retired_at": retiredAt,
+			"expires_at": expiresAt,
+		}).Error
+}
+
+func (r *SigningKeyRepository) DeleteExpiredSigningKeys(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at IS NOT NULL AND expires_at <= ?", now).Delete(&models.SigningKey{}).Error
+}
+
+func NewSigningKeyRepository(db *gorm.DB) models.SigningKeyRepository {
+	return &SigningKeyRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+)
+
+// maxCachedOverrideUsers bounds the cache, stale users are dropped once it is
+// reached
+const maxCachedOverrideUsers = 10000
+
+type cachedOverrides struct {
+	permissions []*models.UserPermission
+	loadedAt    time.Time
+}
+
+// CachedUserPermissionRepository keeps the active overrides of each user in
+// memory so the auth middleware needs no query per request. Writes through it
+// invalidate the user, ttl bounds how long changes made by other instances
+// stay invisible. A ttl of zero never expires.
+type CachedUserPermissionRepository struct {
+	models.UserPermissionRepository
+	ttl time.Duration
+
+	mu    sync.RWMutex
+	users map[uuid.UUID]*cachedOverrides
+	// generation changes on every invalidation so a slow load cannot store
+	// overrides read before a write
+	generation uint64
+}
+
+// GetActiveUserPermissions filters the cached overrides by now, so overrides
+// that expired since they were loaded no longer apply
+func (r *CachedUserPermissionRepository) GetActiveUserPermissions(ctx context.Context, userId uuid.UUID, now time.Time) ([]*models.UserPermission, error) {
+	r.mu.RLock()
+	cached, found := r.users[userId]
+	generation := r.generation
+	r.mu.RUnlock()
+
+	if !found || !r.isFresh(cached) {
+		permissions, err := r.UserPermissionRepository.GetActiveUserPermissions(ctx, userId, now)
+		if err != nil {
+			return nil, err
+		}
+		cached = &cachedOverrides{permissions: permissions, loadedAt: time.Now()}
+		r.store(userId, cached, generation)
+	}
+
+	active := make([]*models.UserPermission, 0, len(cached.permissions))
+	for _, permission := range cached.permissions {
+		if permission.IsActive(now) {
+			active = append(active, permission)
+		}
+	}
+	return active, nil
+}
+
+func (r *CachedUserPermissionRepository) SaveUserPermission(ctx context.Context, permission *models.UserPermission) error {
+	defer r.Invalidate(permission.UserID)
+	return r.UserPermissionRepository.SaveUserPermission(ctx, permission)
+}
+
+func (r *CachedUserPermissionRepository) DeleteUserPermission(ctx context.Context, userId uuid.UUID, permissionId uuid.UUID) (int64, error) {
+	defer r.Invalidate(userId)
+	return r.UserPermissionRepository.DeleteUserPermission(ctx, userId, permissionId)
+}
+
+// Invalidate drops the cached overrides of userId so the next lookup reloads
+// them
+func (r *CachedUserPermissionRepository) Invalidate(userId uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.users, userId)
+	r.generation++
+}
+
+func (r *CachedUserPermissionRepository) isFresh(cached *cachedOverrides) bool {
+	return r.ttl <= 0 || time.Since(cached.loadedAt) < r.ttl
+}
+
+func (r *CachedUserPermissionRepository) store(userId uuid.UUID, cached *cachedOverrides, generation uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		// Invalidated meanwhile, the next lookup loads again
+		return
+	}
+
+	if len(r.users) >= maxCachedOverrideUsers {
+		for id, entry := range r.users {
+			if !r.isFresh(entry) {
+				delete(r.users, id)
+			}
+		}
+		if len(r.users) >= maxCachedOverrideUsers {
+			r.users = map[uuid.UUID]*cachedOverrides{}
+		}
+	}
+	r.users[userId] = cached
+}
+
+func NewCachedUserPermissionRepository(repository models.UserPermissionRepository, ttl time.Duration) *CachedUserPermissionRepository {
+	return &CachedUserPermissionRepository{
+		UserPermissionRepository: repository,
+		ttl:                      ttl,
+		users:                    map[uuid.UUID]*cachedOverrides{},
+	}
+}
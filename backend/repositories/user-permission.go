@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserPermissionRepository struct {
+	db *gorm.DB
+}
+
+func (r *UserPermissionRepository) GetUserPermissions(ctx context.Context, userId uuid.UUID) ([]*models.UserPermission, error) {
+	permissions := []*models.UserPermission{}
+	res := r.db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("permission").
+		Find(&permissions)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return permissions, nil
+}
+
+func (r *UserPermissionRepository) GetActiveUserPermissions(ctx context.Context, userId uuid.UUID, now time.Time) ([]*models.UserPermission, error) {
+	permissions := []*models.UserPermission{}
+	res := r.db.WithContext(ctx).
+		Where("user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userId, now).
+		Find(&permissions)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return permissions, nil
+}
+
+// SaveUserPermission creates the override or replaces the existing one for
+// the same user and permission
+func (r *UserPermissionRepository) SaveUserPermission(ctx context.Context, permission *models.UserPermission) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing := &models.UserPermission{}
+		err := tx.Where("user_id = ? AND permission = ?", permission.UserID, permission.Permission).First(existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := tx.Create(permission).Error; err != nil {
+				return err
+			}
+			return recordAudit(ctx, tx, models.AuditCreate, models.AuditEntityUserPermission, permission.ID, nil, permission)
+		}
+		if err != nil {
+			return err
+		}
+
+		permission.ID = existing.ID
+		permission.CreatedAt = existing.CreatedAt
+		if err := tx.Save(permission).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, models.AuditUpdate, models.AuditEntityUserPermission, permission.ID, existing, permission)
+	})
+}
+
+func (r *UserPermissionRepository) DeleteUserPermission(ctx context.Context, userId uuid.UUID, permissionId uuid.UUID) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing := &models.UserPermission{}
+		err := tx.Where("id = ? AND user_id = ?", permissionId, userId).First(existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		res := tx.Delete(existing)
+		if res.Error != nil {
+			return res.Error
+		}
+		deleted = res.RowsAffected
+
+		return recordAudit(ctx, tx, models.AuditDelete, models.AuditEntityUserPermission, existing.ID, existing, nil)
+	})
+	return deleted, err
+}
+
+func NewUserPermissionRepository(db *gorm.DB) models.UserPermissionRepository {
+	return &UserPermissionRepository{
+		db: db,
+	}
+}
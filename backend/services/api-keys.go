@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/policy"
+	"github.com/DestaAri1/RentAuto/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	apiKeyPrefix         = "ra"
+	apiKeyDefaultExpiry  = 90 * 24 * time.Hour
+	apiKeyTouchInterval  = time.Minute
+	serviceAccountDomain = "service-accounts.local"
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+type APIKeyService struct {
+	repository     models.APIKeyRepository
+	authRepository models.AuthRepository
+	policy         *policy.Policy
+}
+
+// CreateKey issues a key for ownerId. Every scope must be a known permission
+// that the owner's role, or a personal grant, allows today, and that
+// createdBy holds too so nobody can mint keys beyond their own permissions.
+func (s *APIKeyService) CreateKey(ctx context.Context, ownerId uuid.UUID, createdBy uuid.UUID, formData *models.APIKeyForm) (*models.CreatedAPIKey, error) {
+	owner := &models.User{}
+	if err := s.authRepository.GetUserWithRole(ctx, ownerId, owner); err != nil {
+		return nil, err
+	}
+
+	creator := &models.User{}
+	if err := s.authRepository.GetUserWithRole(ctx, createdBy, creator); err != nil {
+		return nil, err
+	}
+
+	// The request context carries the creator's overrides, the owner's are
+	// loaded separately
+	creatorCtx := ctx
+	ctx, err := s.policy.WithUserOverrides(ctx, ownerId)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make([]string, 0, len(formData.Scopes))
+	for _, requested := range formData.Scopes {
+		scope, _ := policy.CanonicalPermission(requested)
+		if !policy.IsKnownPermission(scope) {
+			return nil, fmt.Errorf("unknown permission %q", requested)
+		}
+		if policy.IsAdministrative(scope) {
+			return nil, fmt.Errorf("%q cannot be granted to an API key", scope)
+		}
+		if err := s.policy.CheckPermission(ctx, owner.RoleID, scope); err != nil {
+			return nil, fmt.Errorf("the owner's role does not grant %q", scope)
+		}
+		if err := s.policy.CheckPermission(creatorCtx, creator.RoleID, scope); err != nil {
+			return nil, fmt.Errorf("you can only grant permissions you hold, %q is not one of them", scope)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	expiresIn := apiKeyDefaultExpiry
+	if formData.ExpiresInDays > 0 {
+		expiresIn = time.Duration(formData.ExpiresInDays) * 24 * time.Hour
+	}
+
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %v", err)
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+
+	secret, err := utils.RandomURLToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	rawKey := apiKeyPrefix + "_" + prefix + "_" + secret
+
+	key := &models.APIKey{
+		UserID:    ownerId,
+		Name:      formData.Name,
+		Prefix:    prefix,
+		Hash:      hashAPIKey(rawKey),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(expiresIn),
+	}
+
+	if err := s.repository.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &models.CreatedAPIKey{APIKey: key, Key: rawKey}, nil
+}
+
+func (s *APIKeyService) ListKeys(ctx context.Context, ownerId uuid.UUID) ([]*models.APIKey, error) {
+	return s.repository.GetAPIKeys(ctx, ownerId)
+}
+
+func (s *APIKeyService) RevokeKey(ctx context.Context, ownerId uuid.UUID, keyId uuid.UUID) error {
+	revoked, err := s.repository.RevokeAPIKey(ctx, ownerId, keyId, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if revoked == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// Authenticate resolves a raw key and records when and from where it was used
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string, ip string) (*models.APIKey, error) {
+	parts := strings.SplitN(rawKey, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repository.GetAPIKeyByPrefix(ctx, parts[1])
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashAPIKey(rawKey))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, errors.New("API key has been revoked")
+	}
+	if !now.Before(key.ExpiresAt) {
+		return nil, errors.New("API key has expired")
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval || key.LastUsedIP != ip {
+		if err := s.repository.TouchAPIKey(ctx, key.ID, now, ip); err != nil {
+			log.Printf("Failed to record API key usage: %v", err)
+		}
+	}
+
+	return key, nil
+}
+
+// CreateServiceAccount adds a user that can only authenticate with API keys
+func (s *APIKeyService) CreateServiceAccount(ctx context.Context, formData *models.ServiceAccountForm) (*models.User, error) {
+	user := &models.User{
+		Name:   formData.Name,
+		Email:  uuid.NewString() + "@" + serviceAccountDomain,
+		RoleID: formData.Role,
+	}
+
+	if err := s.repository.CreateServiceAccount(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *APIKeyService) ListServiceAccounts(ctx context.Context) ([]*models.User, error) {
+	return s.repository.GetServiceAccounts(ctx)
+}
+
+func (s *APIKeyService) GetServiceAccount(ctx context.Context, userId uuid.UUID) (*models.User, error) {
+	return s.repository.GetServiceAccount(ctx, userId)
+}
+
+func (s *APIKeyService) DeleteServiceAccount(ctx context.Context, userId uuid.UUID) error {
+	return s.repository.DeleteServiceAccount(ctx, userId)
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewAPIKeyService(repository models.APIKeyRepository, authRepository models.AuthRepository, policy *policy.Policy) models.APIKeyServices {
+	return &APIKeyService{
+		repository:     repository,
+		authRepository: authRepository,
+		policy:         policy,
+	}
+}
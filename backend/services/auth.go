@@ -4,105 +4,145 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"net/http"
 	"time"
 
+	"github.com/DestaAri1/RentAuto/config"
 	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/policy"
 	"github.com/DestaAri1/RentAuto/utils"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-const defaultJWTSecret = "rent-auto-secret-key-2024"
+var (
+	ErrInvalidMFACode     = errors.New("invalid verification code")
+	ErrInvalidChallenge   = errors.New("invalid or expired challenge token")
+	ErrMFANotEnrolled     = errors.New("two-factor authentication is not enrolled")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrMFARequiredForRole = errors.New("two-factor authentication is required for this account")
+)
+
+const (
+	// mfaMaxAttempts is how many codes one challenge token accepts
+	mfaMaxAttempts = 5
+	// mfaMaxFailures invalid codes within mfaFailureWindow lock the user's
+	// MFA step, no matter how many challenges were requested
+	mfaMaxFailures   = 10
+	mfaFailureWindow = 15 * time.Minute
+)
 
 type AuthService struct {
-	repository models.AuthRepository
+	repository    models.AuthRepository
+	mfaRepository models.MFARepository
+	adminPolicy   *policy.AdminPolicy
+	keyManager    models.KeyManager
+	sessions      models.SessionServices
+	passwords     models.PasswordServices
+	blacklist     models.BlacklistRepository
+	config        config.AuthConfig
+	oidc          map[string]*oidcProvider
 }
 
-func (s *AuthService) Login(ctx context.Context, loginData *models.LoginCredentials) (string, *models.User, error) {
+func (s *AuthService) Login(ctx context.Context, loginData *models.LoginCredentials, client models.ClientInfo) (*models.LoginResult, error) {
 	// Get user with role preloaded
 	user, err := s.repository.GetUser(ctx, "email = ?", loginData.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", nil, fmt.Errorf("invalid credentials")
+			return nil, fmt.Errorf("invalid credentials")
 		}
-		return "", nil, err
+		return nil, err
 	}
 
-	// Verify password
-	if !models.MatchesHash(loginData.Password, user.Password) {
-		return "", nil, fmt.Errorf("invalid credentials")
+	// Service accounts only authenticate with API keys
+	if user.IsServiceAccount {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	// Verify password, upgrading legacy hashes on success
+	if !s.passwords.Verify(ctx, user, loginData.Password) {
+		return nil, fmt.Errorf("invalid credentials")
 	}
 
 	// Ensure we have the role data
 	if err := s.repository.GetUserWithRole(ctx, user.ID, user); err != nil {
-		return "", nil, fmt.Errorf("failed to get user role: %v", err)
+		return nil, fmt.Errorf("failed to get user role: %v", err)
 	}
 
-	// Get JWT secret from environment or use default
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = defaultJWTSecret
+	return s.completeLogin(ctx, user, client)
+}
+
+func (s *AuthService) Register(ctx context.Context, registerData *models.AuthCredentials, client models.ClientInfo) (string, *models.User, error) {
+	if !models.IsValidEmail(registerData.Email) {
+		return "", nil, fmt.Errorf("please provide a valid email to register")
 	}
 
-	// Create claims with proper role ID
-	claims := jwt.MapClaims{
-		"id":   user.ID.String(),
-		"role": user.RoleID.String(), // Ensure this is the role ID, not the password
-		"exp":  time.Now().Add(time.Hour * 24).Unix(),
+	if _, err := s.repository.GetUser(ctx, "email = ?", registerData.Email); !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil, fmt.Errorf("the email is already used")
 	}
 
-	token, err := utils.GenerateJWT(claims, jwt.SigningMethodHS256, secret)
+	blacklisted, err := s.blacklist.IsBlacklisted(ctx, registerData.Email, registerData.Phone, registerData.IDNumber)
 	if err != nil {
 		return "", nil, err
 	}
-
-	return token, user, nil
-}
-
-func (s *AuthService) Register(ctx context.Context, registerData *models.AuthCredentials) (string, *models.User, error) {
-	if !models.IsValidEmail(registerData.Email) {
-		return "", nil, fmt.Errorf("please provide a valid email to register")
+	if blacklisted {
+		return "", nil, models.ErrBlacklisted
 	}
 
-	if _, err := s.repository.GetUser(ctx, "email = ?", registerData.Email); !errors.Is(err, gorm.ErrRecordNotFound) {
-		return "", nil, fmt.Errorf("the email is already used")
+	if err := s.passwords.Check(ctx, uuid.Nil, registerData.Password, registerData.Email, registerData.Username); err != nil {
+		return "", nil, err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(registerData.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwords.Hash(registerData.Password)
 	if err != nil {
 		return "", nil, err
 	}
 
-	registerData.Password = string(hashedPassword)
+	registerData.Password = hashedPassword
 
 	user, err := s.repository.RegisterUser(ctx, registerData)
 	if err != nil {
 		return "", nil, err
 	}
 
+	if err := s.passwords.Remember(ctx, user.ID, hashedPassword); err != nil {
+		return "", nil, err
+	}
+
 	// Ensure we have the role data
 	if err := s.repository.GetUserWithRole(ctx, user.ID, user); err != nil {
 		return "", nil, fmt.Errorf("failed to get user role: %v", err)
 	}
 
-	// Get JWT secret from environment or use default
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = defaultJWTSecret
+	token, err := s.issueToken(ctx, user, client)
+	if err != nil {
+		return "", nil, err
 	}
 
-	// Create claims with proper role ID
-	claims := jwt.MapClaims{
-		"id":   user.ID.String(),
-		"role": user.RoleID.String(), // Ensure this is the role ID, not the password
-		"exp":  time.Now().Add(time.Hour * 24).Unix(),
+	return token, user, nil
+}
+
+// VerifyMFALogin finishes a login started by Login using a TOTP or recovery code
+func (s *AuthService) VerifyMFALogin(ctx context.Context, formData *models.MFAChallengeForm, client models.ClientInfo) (string, *models.User, error) {
+	user, challengeId, err := s.resolveChallenge(ctx, formData.ChallengeToken, models.MFAPurposeLogin)
+	if err != nil {
+		return "", nil, err
 	}
 
-	fmt.Printf("Register - Claims: %+v\n", claims)
+	mfa, err := s.mfaRepository.GetMFA(ctx, user.ID)
+	if err != nil || !mfa.Enabled {
+		return "", nil, ErrMFANotEnrolled
+	}
+
+	err = s.attemptChallenge(ctx, challengeId, func() error {
+		return s.verifyCode(ctx, mfa, formData.Code, true)
+	})
+	if err != nil {
+		return "", nil, err
+	}
 
-	token, err := utils.GenerateJWT(claims, jwt.SigningMethodHS256, secret)
+	token, err := s.issueToken(ctx, user, client)
 	if err != nil {
 		return "", nil, err
 	}
@@ -110,8 +150,551 @@ func (s *AuthService) Register(ctx context.Context, registerData *models.AuthCre
 	return token, user, nil
 }
 
-func NewAuthService(repository models.AuthRepository) models.AuthServices {
+// StartMFASetup enrols a user whose role requires MFA but who has not set it up yet
+func (s *AuthService) StartMFASetup(ctx context.Context, formData *models.MFASetupForm) (*models.MFAEnrollment, error) {
+	user, _, err := s.resolveSetupChallenge(ctx, formData.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.EnrollMFA(ctx, user.ID)
+}
+
+// CompleteMFASetup confirms the enrolment started by StartMFASetup and logs the user in
+func (s *AuthService) CompleteMFASetup(ctx context.Context, formData *models.MFAChallengeForm, client models.ClientInfo) (string, *models.User, []string, error) {
+	user, challengeId, err := s.resolveSetupChallenge(ctx, formData.ChallengeToken)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	mfa, err := s.pendingMFA(ctx, user.ID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	err = s.attemptChallenge(ctx, challengeId, func() error {
+		return s.verifyCode(ctx, mfa, formData.Code, false)
+	})
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	codes, err := s.activateMFA(ctx, mfa)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	token, err := s.issueToken(ctx, user, client)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return token, user, codes, nil
+}
+
+// EnrollMFA generates a new pending secret. It becomes active after ConfirmMFA.
+func (s *AuthService) EnrollMFA(ctx context.Context, userId uuid.UUID) (*models.MFAEnrollment, error) {
+	existing, err := s.mfaRepository.GetMFA(ctx, userId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if existing != nil && existing.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	user := &models.User{}
+	if err := s.repository.GetUserWithRole(ctx, userId, user); err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	mfa := &models.UserMFA{
+		UserID: userId,
+		Secret: secret,
+	}
+
+	if err := s.mfaRepository.SaveMFA(ctx, mfa); err != nil {
+		return nil, err
+	}
+
+	return &models.MFAEnrollment{
+		Secret: secret,
+		URI:    utils.TOTPURI(s.config.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA activates a pending enrolment and returns fresh recovery codes
+func (s *AuthService) ConfirmMFA(ctx context.Context, userId uuid.UUID, code string) ([]string, error) {
+	mfa, err := s.pendingMFA(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.attemptAccountCode(ctx, userId, func() error {
+		return s.verifyCode(ctx, mfa, code, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.activateMFA(ctx, mfa)
+}
+
+// pendingMFA returns the enrolment of a user that still has to be confirmed
+func (s *AuthService) pendingMFA(ctx context.Context, userId uuid.UUID) (*models.UserMFA, error) {
+	mfa, err := s.mfaRepository.GetMFA(ctx, userId)
+	if err != nil {
+		return nil, ErrMFANotEnrolled
+	}
+
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	return mfa, nil
+}
+
+// activateMFA enables a verified enrolment and returns fresh recovery codes
+func (s *AuthService) activateMFA(ctx context.Context, mfa *models.UserMFA) ([]string, error) {
+	codes, err := s.resetRecoveryCodes(mfa)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	mfa.Enabled = true
+	mfa.ConfirmedAt = &now
+
+	if err := s.mfaRepository.SaveMFA(ctx, mfa); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code after verifying a current TOTP code
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userId uuid.UUID, code string) ([]string, error) {
+	mfa, err := s.mfaRepository.GetMFA(ctx, userId)
+	if err != nil || !mfa.Enabled {
+		return nil, ErrMFANotEnrolled
+	}
+
+	err = s.attemptAccountCode(ctx, userId, func() error {
+		return s.verifyCode(ctx, mfa, code, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := s.resetRecoveryCodes(mfa)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepository.SaveMFA(ctx, mfa); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableMFA removes the second factor unless the user's role requires it
+func (s *AuthService) DisableMFA(ctx context.Context, userId uuid.UUID, code string) error {
+	mfa, err := s.mfaRepository.GetMFA(ctx, userId)
+	if err != nil || !mfa.Enabled {
+		return ErrMFANotEnrolled
+	}
+
+	user := &models.User{}
+	if err := s.repository.GetUserWithRole(ctx, userId, user); err != nil {
+		return err
+	}
+
+	required, err := s.mfaRequired(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	if required {
+		return ErrMFARequiredForRole
+	}
+
+	err = s.attemptAccountCode(ctx, userId, func() error {
+		return s.verifyCode(ctx, mfa, code, true)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mfaRepository.DeleteMFA(ctx, userId)
+}
+
+// ChangePassword sets a new password after verifying the current one and signs
+// out every other session of the user
+func (s *AuthService) ChangePassword(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID, formData *models.ChangePasswordForm) error {
+	user := &models.User{}
+	if err := s.repository.GetUserWithRole(ctx, userId, user); err != nil {
+		return err
+	}
+
+	if !models.MatchesHash(formData.CurrentPassword, user.Password) {
+		return errors.New("current password is incorrect")
+	}
+
+	if err := s.passwords.SetPassword(ctx, user, formData.NewPassword); err != nil {
+		return err
+	}
+
+	_, err := s.sessions.RevokeOtherSessions(ctx, userId, sessionId)
+	return err
+}
+
+// Impersonate issues a short lived token that acts as userId for the support
+// user impersonatorId. The token carries both users, the imp claim names the
+// impersonator. Administrators, protected users and service accounts cannot
+// be impersonated so impersonation never gains permissions.
+func (s *AuthService) Impersonate(ctx context.Context, impersonatorId uuid.UUID, userId uuid.UUID, client models.ClientInfo) (*models.ImpersonationResult, error) {
+	if impersonatorId == userId {
+		return nil, errors.New("you cannot impersonate yourself")
+	}
+
+	user := &models.User{}
+	if err := s.repository.GetUserWithRole(ctx, userId, user); err != nil {
+		return nil, err
+	}
+
+	if user.IsProtected || user.IsServiceAccount {
+		return nil, models.ErrCannotImpersonate
+	}
+
+	// Judge the target by its own grants, not by those of the support user
+	targetCtx, err := s.adminPolicy.WithUserOverrides(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	isAdmin, err := s.adminPolicy.IsAdmin(targetCtx, user.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if isAdmin {
+		return nil, models.ErrCannotImpersonate
+	}
+
+	expiresAt := time.Now().Add(s.config.ImpersonationTTL.Duration)
+
+	session, err := s.sessions.CreateImpersonationSession(ctx, user.ID, impersonatorId, client, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+
+	claims := jwt.MapClaims{
+		"id":   user.ID.String(),
+		"role": user.RoleID.String(),
+		"sid":  session.ID.String(),
+		"imp":  impersonatorId.String(),
+		"exp":  expiresAt.Unix(),
+	}
+
+	token, err := s.keyManager.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ImpersonationResult{
+		Token:          token,
+		ExpiresAt:      expiresAt,
+		ImpersonatorID: impersonatorId,
+		User:           user,
+	}, nil
+}
+
+// completeLogin issues the token for an authenticated user, or an MFA
+// challenge when a second factor is enabled or required for the role
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, client models.ClientInfo) (*models.LoginResult, error) {
+	// Service accounts only authenticate with API keys, whatever identity they have
+	if user.IsServiceAccount {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	// Checked before any MFA step so a suspended user gets a clear answer
+	if user.StatusAt(time.Now()) == models.UserStatusSuspended {
+		return nil, models.ErrAccountSuspended
+	}
+
+	mfa, err := s.mfaRepository.GetMFA(ctx, user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Second step needed: the password alone never issues a token for MFA users
+	if mfa != nil && mfa.Enabled {
+		challenge, err := s.issueChallenge(ctx, user, models.MFAPurposeLogin)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{MFARequired: true, ChallengeToken: challenge}, nil
+	}
+
+	required, err := s.mfaRequired(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if required {
+		challenge, err := s.issueChallenge(ctx, user, models.MFAPurposeSetup)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{MFASetupRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	token, err := s.issueToken(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LoginResult{Token: token, User: user}, nil
+}
+
+// mfaRequired reports whether the user's role, or a personal grant, makes
+// the user an administrator who is forced to use MFA
+func (s *AuthService) mfaRequired(ctx context.Context, user *models.User) (bool, error) {
+	if !s.config.RequireAdminMFA || s.adminPolicy == nil {
+		return false, nil
+	}
+
+	ctx, err := s.adminPolicy.WithUserOverrides(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return s.adminPolicy.IsAdmin(ctx, user.RoleID)
+}
+
+// verifyCode accepts a TOTP code, or a single-use recovery code when allowRecovery is set
+func (s *AuthService) verifyCode(ctx context.Context, mfa *models.UserMFA, code string, allowRecovery bool) error {
+	if step, ok := utils.ValidateTOTP(mfa.Secret, code, time.Now(), mfa.LastUsedStep); ok {
+		mfa.LastUsedStep = step
+		return s.mfaRepository.SaveMFA(ctx, mfa)
+	}
+
+	if allowRecovery {
+		hashed := utils.HashRecoveryCode(code)
+		for i, stored := range mfa.RecoveryCodes {
+			if stored == hashed {
+				mfa.RecoveryCodes = append(mfa.RecoveryCodes[:i], mfa.RecoveryCodes[i+1:]...)
+				return s.mfaRepository.SaveMFA(ctx, mfa)
+			}
+		}
+	}
+
+	return ErrInvalidMFACode
+}
+
+func (s *AuthService) resetRecoveryCodes(mfa *models.UserMFA) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(utils.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashed := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashed = append(hashed, utils.HashRecoveryCode(code))
+	}
+	mfa.RecoveryCodes = hashed
+
+	return codes, nil
+}
+
+// issueChallenge creates a short lived token that only identifies the user for
+// the MFA step, its jti is the stored challenge that counts the attempts
+func (s *AuthService) issueChallenge(ctx context.Context, user *models.User, purpose string) (string, error) {
+	if err := s.checkMFAFailures(ctx, user.ID); err != nil {
+		return "", err
+	}
+
+	challenge := &models.MFAChallenge{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(s.config.MFAChallengeTTL.Duration),
+	}
+	if err := s.mfaRepository.CreateChallenge(ctx, challenge, mfaFailureWindow); err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"sub":     user.ID.String(),
+		"jti":     challenge.ID.String(),
+		"purpose": purpose,
+		"exp":     challenge.ExpiresAt.Unix(),
+	}
+
+	return s.keyManager.Sign(claims)
+}
+
+func (s *AuthService) resolveChallenge(ctx context.Context, challengeToken string, purpose string) (*models.User, uuid.UUID, error) {
+	token, err := s.keyManager.Parse(challengeToken)
+	if err != nil {
+		return nil, uuid.Nil, ErrInvalidChallenge
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return nil, uuid.Nil, ErrInvalidChallenge
+	}
+
+	subject, _ := claims["sub"].(string)
+	userId, err := uuid.Parse(subject)
+	if err != nil {
+		return nil, uuid.Nil, ErrInvalidChallenge
+	}
+
+	jti, _ := claims["jti"].(string)
+	challengeId, err := uuid.Parse(jti)
+	if err != nil {
+		return nil, uuid.Nil, ErrInvalidChallenge
+	}
+
+	challenge, err := s.mfaRepository.GetChallenge(ctx, challengeId)
+	if err != nil {
+		return nil, uuid.Nil, ErrInvalidChallenge
+	}
+	if challenge.UserID != userId || challenge.Purpose != purpose || challenge.UsedAt != nil ||
+		challenge.Attempts >= mfaMaxAttempts || !time.Now().Before(challenge.ExpiresAt) {
+		return nil, uuid.Nil, ErrInvalidChallenge
+	}
+
+	if err := s.checkMFAFailures(ctx, userId); err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	user := &models.User{}
+	if err := s.repository.GetUserWithRole(ctx, userId, user); err != nil {
+		return nil, uuid.Nil, ErrInvalidChallenge
+	}
+
+	return user, challenge.ID, nil
+}
+
+// resolveSetupChallenge also checks that the user still has to enrol, the
+// role may have changed since the challenge was issued
+func (s *AuthService) resolveSetupChallenge(ctx context.Context, challengeToken string) (*models.User, uuid.UUID, error) {
+	user, challengeId, err := s.resolveChallenge(ctx, challengeToken, models.MFAPurposeSetup)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	required, err := s.mfaRequired(ctx, user)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	if !required {
+		return nil, uuid.Nil, ErrInvalidChallenge
+	}
+
+	return user, challengeId, nil
+}
+
+// attemptChallenge counts the attempt before verify runs so parallel requests
+// cannot exceed mfaMaxAttempts, a successful verify uses the challenge up
+func (s *AuthService) attemptChallenge(ctx context.Context, challengeId uuid.UUID, verify func() error) error {
+	now := time.Now()
+
+	reserved, err := s.mfaRepository.ReserveChallengeAttempt(ctx, challengeId, mfaMaxAttempts, now)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return ErrInvalidChallenge
+	}
+
+	if err := verify(); err != nil {
+		return err
+	}
+
+	used, err := s.mfaRepository.UseChallenge(ctx, challengeId, now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidChallenge
+	}
+
+	return nil
+}
+
+// attemptAccountCode verifies a code entered by a signed in user through a
+// single attempt challenge, so these codes count towards mfaMaxFailures like
+// the ones entered while logging in
+func (s *AuthService) attemptAccountCode(ctx context.Context, userId uuid.UUID, verify func() error) error {
+	if err := s.checkMFAFailures(ctx, userId); err != nil {
+		return err
+	}
+
+	challenge := &models.MFAChallenge{
+		UserID:    userId,
+		Purpose:   models.MFAPurposeAccount,
+		ExpiresAt: time.Now().Add(s.config.MFAChallengeTTL.Duration),
+	}
+	if err := s.mfaRepository.CreateChallenge(ctx, challenge, mfaFailureWindow); err != nil {
+		return err
+	}
+
+	return s.attemptChallenge(ctx, challenge.ID, verify)
+}
+
+func (s *AuthService) checkMFAFailures(ctx context.Context, userId uuid.UUID) error {
+	failures, err := s.mfaRepository.CountChallengeFailures(ctx, userId, time.Now().Add(-mfaFailureWindow))
+	if err != nil {
+		return err
+	}
+	if failures >= mfaMaxFailures {
+		return models.ErrTooManyMFAAttempts
+	}
+	return nil
+}
+
+// issueToken starts a session for the device and returns an access token bound to it
+func (s *AuthService) issueToken(ctx context.Context, user *models.User, client models.ClientInfo) (string, error) {
+	// Every login path ends here, including the MFA steps
+	if user.StatusAt(time.Now()) == models.UserStatusSuspended {
+		return "", models.ErrAccountSuspended
+	}
+
+	expiresAt := time.Now().Add(s.config.AccessTokenTTL.Duration)
+
+	session, err := s.sessions.CreateSession(ctx, user.ID, client, expiresAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %v", err)
+	}
+
+	// Create claims with proper role ID
+	claims := jwt.MapClaims{
+		"id":   user.ID.String(),
+		"role": user.RoleID.String(), // Ensure this is the role ID, not the password
+		"sid":  session.ID.String(),
+		"exp":  expiresAt.Unix(),
+	}
+
+	return s.keyManager.Sign(claims)
+}
+
+func NewAuthService(repository models.AuthRepository, mfaRepository models.MFARepository, adminPolicy *policy.AdminPolicy, keyManager models.KeyManager, sessions models.SessionServices, passwords models.PasswordServices, blacklist models.BlacklistRepository, config config.AuthConfig) models.AuthServices {
 	return &AuthService{
-		repository: repository,
+		repository:    repository,
+		mfaRepository: mfaRepository,
+		adminPolicy:   adminPolicy,
+		keyManager:    keyManager,
+		sessions:      sessions,
+		passwords:     passwords,
+		blacklist:     blacklist,
+		config:        config,
+		oidc:          newOIDCProviders(config.OIDCProviders, &http.Client{Timeout: 10 * time.Second}),
 	}
-}
\ No newline at end of file
+}
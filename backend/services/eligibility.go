@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+)
+
+type EligibilityService struct {
+	profiles       models.CustomerProfileRepository
+	authRepository models.AuthRepository
+	blacklist      models.BlacklistRepository
+	settings       models.SettingsServices
+}
+
+// CheckEligibility collects every reason that stops the booking so the
+// customer can fix them at once. The age counts on the first rental day, the
+// licence must stay valid until the last.
+func (s *EligibilityService) CheckEligibility(ctx context.Context, userId uuid.UUID, start time.Time, end time.Time) (*models.Eligibility, error) {
+	user := &models.User{}
+	if err := s.authRepository.GetUserWithRole(ctx, userId, user); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.profiles.GetProfile(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons := []string{}
+
+	if user.StatusAt(time.Now()) != models.UserStatusActive {
+		reasons = append(reasons, "This account cannot book cars, please contact support")
+	} else {
+		blacklisted, err := s.blacklist.IsBlacklisted(ctx, user.Email, profile.Phone, profile.LicenceNumber)
+		if err != nil {
+			return nil, err
+		}
+		if blacklisted {
+			reasons = append(reasons, "This account cannot book cars, please contact support")
+		}
+	}
+
+	missing := []string{}
+	if profile.Phone == "" {
+		missing = append(missing, "phone")
+	}
+	if profile.DateOfBirth == nil {
+		missing = append(missing, "date of birth")
+	}
+	if profile.LicenceNumber == "" {
+		missing = append(missing, "driving licence")
+	}
+	if len(missing) > 0 {
+		reasons = append(reasons, "Complete your profile: "+strings.Join(missing, ", "))
+	}
+
+	if minAge := int(s.settings.Int(models.SettingMinDriverAge)); profile.DateOfBirth != nil && profile.AgeAt(start) < minAge {
+		reasons = append(reasons, fmt.Sprintf("The driver must be at least %d years old", minAge))
+	}
+
+	if profile.LicenceNumber != "" {
+		classes := s.settings.Strings(models.SettingLicenceClasses)
+		if !containsFold(classes, profile.LicenceClass) {
+			reasons = append(reasons, fmt.Sprintf("Licence class %s does not allow renting a car, accepted classes: %s", profile.LicenceClass, strings.Join(classes, ", ")))
+		}
+
+		// The licence is valid through its expiry date
+		if profile.LicenceExpiresAt != nil && !end.Before(profile.LicenceExpiresAt.AddDate(0, 0, 1)) {
+			reasons = append(reasons, "The driving licence expires before the end of the rental")
+		}
+	}
+
+	return &models.Eligibility{
+		Eligible: len(reasons) == 0,
+		Reasons:  reasons,
+	}, nil
+}
+
+func containsFold(items []string, value string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func NewEligibilityService(profiles models.CustomerProfileRepository, authRepository models.AuthRepository, blacklist models.BlacklistRepository, settings models.SettingsServices) models.EligibilityServices {
+	return &EligibilityService{
+		profiles:       profiles,
+		authRepository: authRepository,
+		blacklist:      blacklist,
+		settings:       settings,
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/utils"
+	"github.com/google/uuid"
+)
+
+type InvitationService struct {
+	repository     models.InvitationRepository
+	authRepository models.AuthRepository
+	passwords      models.PasswordServices
+	sessions       models.SessionServices
+	mailer         models.Mailer
+	publicURL      string
+	ttl            time.Duration
+}
+
+func (s *InvitationService) Invite(ctx context.Context, user *models.User, invitedBy uuid.UUID) error {
+	return s.invite(ctx, user, invitedBy, false)
+}
+
+func (s *InvitationService) SendPasswordReset(ctx context.Context, userId uuid.UUID, requestedBy uuid.UUID) error {
+	user := &models.User{}
+	if err := s.authRepository.GetUserWithRole(ctx, userId, user); err != nil {
+		return models.ErrInvitationUserNotFound
+	}
+	if user.IsServiceAccount {
+		return models.ErrNoPasswordToReset
+	}
+
+	return s.invite(ctx, user, requestedBy, true)
+}
+
+func (s *InvitationService) invite(ctx context.Context, user *models.User, invitedBy uuid.UUID, reset bool) error {
+	token, err := utils.RandomURLToken(32)
+	if err != nil {
+		return err
+	}
+
+	invitation := &models.UserInvitation{
+		UserID:    user.ID,
+		TokenHash: hashInvitationToken(token),
+		CreatedBy: invitedBy,
+		ExpiresAt: time.Now().Add(s.ttl),
+		Reset:     reset,
+	}
+	if err := s.repository.CreateInvitation(ctx, invitation); err != nil {
+		return err
+	}
+
+	link := s.publicURL + "/invitations/accept?token=" + url.QueryEscape(token)
+	expires := invitation.ExpiresAt.Format("2 January 2006 15:04 MST")
+	mail := &models.Mail{
+		To:      user.Email,
+		Subject: "You have been invited to RentAuto",
+		Body: fmt.Sprintf("Hello %s,\n\nAn account has been created for you at RentAuto. "+
+			"Choose your password with the link below, it can be used once until %s.\n\n%s\n",
+			user.Name, expires, link),
+	}
+	if reset {
+		mail.Subject = "Reset your RentAuto password"
+		mail.Body = fmt.Sprintf("Hello %s,\n\nAn administrator asked for your RentAuto password to be reset. "+
+			"Choose a new password with the link below, it can be used once until %s. "+
+			"Your current password keeps working until then.\n\n%s\n",
+			user.Name, expires, link)
+	}
+	return s.mailer.Send(ctx, mail)
+}
+
+// Accept sets the invited user's password. The password is checked before the
+// invitation is used up so a rejected password can be retried with the same link.
+func (s *InvitationService) Accept(ctx context.Context, formData *models.AcceptInvitationForm) error {
+	invitation, err := s.repository.GetInvitationByHash(ctx, hashInvitationToken(formData.Token))
+	if err != nil {
+		return models.ErrInvalidInvitation
+	}
+
+	now := time.Now()
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil || now.After(invitation.ExpiresAt) {
+		return models.ErrInvalidInvitation
+	}
+
+	user := &models.User{}
+	if err := s.authRepository.GetUserWithRole(ctx, invitation.UserID, user); err != nil {
+		return models.ErrInvalidInvitation
+	}
+
+	if err := s.passwords.Check(ctx, user.ID, formData.Password, user.Email, user.Name); err != nil {
+		return err
+	}
+
+	accepted, err := s.repository.AcceptInvitation(ctx, invitation.ID, now)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return models.ErrInvalidInvitation
+	}
+
+	if err := s.passwords.SetPassword(ctx, user, formData.Password); err != nil {
+		return err
+	}
+
+	// A reset signs the user out everywhere, like changing the password does
+	if invitation.Reset {
+		if _, err := s.sessions.RevokeAllSessions(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *InvitationService) GetPendingInvitations(ctx context.Context, page *models.PageQuery) (*models.Page, error) {
+	return s.repository.GetPendingInvitations(ctx, page)
+}
+
+func (s *InvitationService) Resend(ctx context.Context, invitationId uuid.UUID, invitedBy uuid.UUID) error {
+	invitation, err := s.repository.GetPendingInvitation(ctx, invitationId)
+	if err != nil {
+		return err
+	}
+
+	user := &models.User{}
+	if err := s.authRepository.GetUserWithRole(ctx, invitation.UserID, user); err != nil {
+		return models.ErrInvitationNotPending
+	}
+
+	return s.invite(ctx, user, invitedBy, invitation.Reset)
+}
+
+func (s *InvitationService) Revoke(ctx context.Context, invitationId uuid.UUID) error {
+	return s.repository.RevokeInvitation(ctx, invitationId, time.Now())
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewInvitationService(repository models.InvitationRepository, authRepository models.AuthRepository, passwords models.PasswordServices, sessions models.SessionServices, mailer models.Mailer, publicURL string, ttl time.Duration) models.InvitationServices {
+	return &InvitationService{
+		repository:     repository,
+		authRepository: authRepository,
+		passwords:      passwords,
+		sessions:       sessions,
+		mailer:         mailer,
+		publicURL:      strings.TrimRight(publicURL, "/"),
+		ttl:            ttl,
+	}
+}
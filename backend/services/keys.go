@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/config"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// reloadCooldown limits how often an unknown kid triggers a reload from the database
+const reloadCooldown = 30 * time.Second
+
+var ErrUnknownKey = errors.New("token signed with an unknown key")
+
+type signingKey struct {
+	record    *models.SigningKey
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+type KeyManagerService struct {
+	repository models.SigningKeyRepository
+	config     config.AuthConfig
+
+	mu         sync.RWMutex
+	keys       map[string]*signingKey
+	current    *signingKey
+	lastReload time.Time
+}
+
+// Sign issues a token with the current signing key and its kid header
+func (m *KeyManagerService) Sign(claims jwt.MapClaims) (string, error) {
+	m.mu.RLock()
+	key := m.current
+	m.mu.RUnlock()
+
+	if key == nil {
+		return "", errors.New("no signing key available")
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.record.Kid
+
+	tokenString, err := token.SignedString(key.signKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %v", err)
+	}
+
+	return tokenString, nil
+}
+
+// Parse verifies a token against the active and retired keys. Only the
+// configured algorithms are accepted and the alg header must match the key.
+// Tokens without a kid are refused, they predate sessions and key rotation.
+func (m *KeyManagerService) Parse(tokenString string) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, ErrUnknownKey
+		}
+
+		key := m.lookup(kid)
+		if key == nil {
+			return nil, ErrUnknownKey
+		}
+
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return key.verifyKey, nil
+	}, jwt.WithValidMethods(m.config.AllowedAlgorithms))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return token, nil
+}
+
+// JWKS publishes the public keys of every non-expired asymmetric key
+func (m *KeyManagerService) JWKS() models.JSONWebKeySet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := models.JSONWebKeySet{Keys: []models.JSONWebKey{}}
+	for _, key := range m.keys {
+		params, ok := utils.PublicJWKParams(key.verifyKey)
+		if !ok {
+			continue
+		}
+
+		set.Keys = append(set.Keys, models.JSONWebKey{
+			Kty: params["kty"],
+			Kid: key.record.Kid,
+			Use: "sig",
+			Alg: key.method.Alg(),
+			N:   params["n"],
+			E:   params["e"],
+			Crv: params["crv"],
+			X:   params["x"],
+		})
+	}
+
+	return set
+}
+
+// Rotate creates a new signing key and retires the current one. The retired key
+// keeps verifying tokens until every token it signed has expired.
+func (m *KeyManagerService) Rotate(ctx context.Context) error {
+	privateKey, publicKey, err := utils.GenerateSigningKey(m.config.SigningAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	record := &models.SigningKey{
+		Kid:        uuid.NewString(),
+		Algorithm:  m.config.SigningAlgorithm,
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := m.repository.CreateSigningKey(ctx, record); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	active := []string{}
+	for kid, key := range m.keys {
+		if key.record.RetiredAt == nil {
+			active = append(active, kid)
+		}
+	}
+	m.mu.RUnlock()
+
+	// Retire every previously active key, including ones left over from another algorithm
+	now := time.Now()
+	for _, kid := range active {
+		if err := m.repository.RetireSigningKey(ctx, kid, now, now.Add(m.maxTokenTTL())); err != nil {
+			return err
+		}
+	}
+
+	if err := m.repository.DeleteExpiredSigningKeys(ctx, time.Now()); err != nil {
+		return err
+	}
+
+	return m.reload(ctx)
+}
+
+// StartRotation rotates the signing key whenever it is older than the configured
+// interval. It also reloads keys so rotations by other instances are picked up.
+func (m *KeyManagerService) StartRotation(ctx context.Context) {
+	interval := m.config.KeyRotationInterval.Duration
+	if interval <= 0 {
+		return
+	}
+
+	check := interval / 24
+	if check < time.Minute {
+		check = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(check)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.reload(ctx); err != nil {
+					log.Printf("Failed to reload signing keys: %v", err)
+					continue
+				}
+
+				m.mu.RLock()
+				current := m.current
+				m.mu.RUnlock()
+
+				if current == nil || time.Since(current.record.CreatedAt) >= interval {
+					if err := m.Rotate(ctx); err != nil {
+						log.Printf("Failed to rotate signing key: %v", err)
+					} else {
+						log.Println("Signing key rotated")
+					}
+				}
+			}
+		}
+	}()
+}
+
+func (m *KeyManagerService) lookup(kid string) *signingKey {
+	m.mu.RLock()
+	key, ok := m.keys[kid]
+	lastReload := m.lastReload
+	m.mu.RUnlock()
+
+	if ok {
+		return key
+	}
+
+	// Another instance may have rotated; reload at most once per cooldown
+	if time.Since(lastReload) < reloadCooldown {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := m.reload(ctx); err != nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keys[kid]
+}
+
+func (m *KeyManagerService) reload(ctx context.Context) error {
+	records, err := m.repository.GetSigningKeys(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(records))
+	var current *signingKey
+
+	for _, record := range records {
+		method, err := utils.SigningMethod(record.Algorithm)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", record.Kid, err)
+			continue
+		}
+
+		signKey, verifyKey, err := utils.ParseSigningKey(record.Algorithm, record.PrivateKey)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", record.Kid, err)
+			continue
+		}
+
+		key := &signingKey{
+			record:    record,
+			method:    method,
+			signKey:   signKey,
+			verifyKey: verifyKey,
+		}
+		keys[record.Kid] = key
+
+		// Newest active key with the configured algorithm signs new tokens
+		if record.RetiredAt == nil && record.Algorithm == m.config.SigningAlgorithm {
+			if current == nil || record.CreatedAt.After(current.record.CreatedAt) {
+				current = key
+			}
+		}
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.current = current
+	m.lastReload = time.Now()
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *KeyManagerService) maxTokenTTL() time.Duration {
+	ttl := m.config.AccessTokenTTL.Duration
+	if m.config.MFAChallengeTTL.Duration > ttl {
+		ttl = m.config.MFAChallengeTTL.Duration
+	}
+	return ttl
+}
+
+// NewKeyManager loads the stored keys and creates a first key when none can sign
+func NewKeyManager(ctx context.Context, repository models.SigningKeyRepository, config config.AuthConfig) (*KeyManagerService, error) {
+	manager := &KeyManagerService{
+		repository: repository,
+		config:     config,
+		keys:       map[string]*signingKey{},
+	}
+
+	if err := manager.reload(ctx); err != nil {
+		return nil, err
+	}
+
+	if manager.current == nil {
+		if err := manager.Rotate(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return manager, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/config"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKeyStore keeps the signing keys in memory
+type signingKeyStore struct {
+	keys []*models.SigningKey
+}
+
+func (r *signingKeyStore) GetSigningKeys(ctx context.Context, now time.Time) ([]*models.SigningKey, error) {
+	return r.keys, nil
+}
+
+func (r *signingKeyStore) CreateSigningKey(ctx context.Context, key *models.SigningKey) error {
+	r.keys = append(r.keys, key)
+	return nil
+}
+
+func (r *signingKeyStore) RetireSigningKey(ctx context.Context, kid string, retiredAt time.Time, expiresAt time.Time) error {
+	for _, key := range r.keys {
+		if key.Kid == kid {
+			key.RetiredAt = &retiredAt
+			key.ExpiresAt = &expiresAt
+		}
+	}
+	return nil
+}
+
+func (r *signingKeyStore) DeleteExpiredSigningKeys(ctx context.Context, now time.Time) error {
+	return nil
+}
+
+func TestKeyManagerParse(t *testing.T) {
+	// HS256 is allowed so a token can claim it for the EdDSA key
+	manager, err := NewKeyManager(context.Background(), &signingKeyStore{}, config.AuthConfig{
+		SigningAlgorithm:  "EdDSA",
+		AllowedAlgorithms: []string{"EdDSA", "HS256"},
+		AccessTokenTTL:    config.Duration{Duration: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := manager.current
+	kid := current.record.Kid
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{"sub": "user", "exp": time.Now().Add(time.Minute).Unix()}
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, claims jwt.MapClaims, key interface{}) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	_, otherEd25519, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := []byte(current.verifyKey.(ed25519.PublicKey))
+
+	valid, err := manager.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired := claims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: valid},
+		{name: "missing kid", token: sign(jwt.SigningMethodEdDSA, "", claims(), current.signKey), wantErr: ErrUnknownKey},
+		{name: "unknown kid", token: sign(jwt.SigningMethodEdDSA, "unknown", claims(), current.signKey), wantErr: ErrUnknownKey},
+		{name: "signed by another key", token: sign(jwt.SigningMethodEdDSA, kid, claims(), otherEd25519), wantErr: jwt.ErrTokenSignatureInvalid},
+		{name: "allowed algorithm that does not match the key", token: sign(jwt.SigningMethodHS256, kid, claims(), publicKey), wantErr: jwt.ErrTokenUnverifiable},
+		{name: "algorithm not allowed", token: sign(jwt.SigningMethodRS256, kid, claims(), rsaKey), wantErr: jwt.ErrTokenSignatureInvalid},
+		{name: "alg none", token: sign(jwt.SigningMethodNone, kid, claims(), jwt.UnsafeAllowNoneSignatureType), wantErr: jwt.ErrTokenSignatureInvalid},
+		{name: "expired", token: sign(jwt.SigningMethodEdDSA, kid, expired, current.signKey), wantErr: jwt.ErrTokenExpired},
+		{name: "malformed", token: "not.a.token", wantErr: jwt.ErrTokenMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := manager.Parse(tt.token)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if token.Claims.(jwt.MapClaims)["sub"] != "user" {
+					t.Fatalf("claims = %v", token.Claims)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
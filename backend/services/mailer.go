@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/config"
+	"github.com/DestaAri1/RentAuto/models"
+)
+
+// SMTPMailer sends email through the configured SMTP server, STARTTLS is used
+// whenever the server offers it
+type SMTPMailer struct {
+	config config.MailConfig
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message *models.Mail) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %v", err)
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	if err := smtp.SendMail(addr, auth, from.Address, []string{message.To}, formatMail(m.config.From, message)); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+
+	return nil
+}
+
+// LogMailer writes emails to the log, it stands in when no SMTP server is configured
+type LogMailer struct{}
+
+func (m *LogMailer) Send(ctx context.Context, message *models.Mail) error {
+	log.Printf("Email to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+func formatMail(from string, message *models.Mail) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + message.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", message.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// NewMailer uses SMTP when a host is configured and the log otherwise
+func NewMailer(cfg config.MailConfig) models.Mailer {
+	if cfg.Host == "" {
+		log.Printf("No mail.host configured, emails are written to the log")
+		return &LogMailer{}
+	}
+
+	return &SMTPMailer{
+		config: cfg,
+	}
+}
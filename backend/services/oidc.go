@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/config"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	oidcStateTTL     = 10 * time.Minute
+	oidcDiscoveryTTL = time.Hour
+	oidcKeysCooldown = time.Minute
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidState    = errors.New("invalid or expired login state")
+)
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcProvider struct {
+	config config.OIDCProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	discoveredAt  time.Time
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func newOIDCProviders(providers []config.OIDCProviderConfig, client *http.Client) map[string]*oidcProvider {
+	registry := make(map[string]*oidcProvider, len(providers))
+	for _, provider := range providers {
+		registry[provider.Name] = &oidcProvider{
+			config: provider,
+			client: client,
+			keys:   map[string]interface{}{},
+		}
+	}
+	return registry
+}
+
+// OIDCProviders lists the configured provider names
+func (s *AuthService) OIDCProviders() []string {
+	names := make([]string, 0, len(s.oidc))
+	for name := range s.oidc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OIDCAuthorize starts an authorization code flow with PKCE. The returned state
+// token carries the verifier and nonce and must come back with the callback,
+// as well as the signed in user when the flow links an identity.
+func (s *AuthService) OIDCAuthorize(ctx context.Context, providerName string, linkUserId uuid.UUID) (*models.OIDCAuthorization, error) {
+	provider, ok := s.oidc[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	discovery, err := provider.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := utils.RandomURLToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := utils.RandomURLToken(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := utils.RandomURLToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	scopes := provider.config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.config.ClientID)
+	query.Set("redirect_uri", provider.config.RedirectURL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	stateClaims := jwt.MapClaims{
+		"purpose":  models.OIDCPurposeState,
+		"provider": providerName,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"exp":      time.Now().Add(oidcStateTTL).Unix(),
+	}
+	if linkUserId != uuid.Nil {
+		stateClaims["link"] = linkUserId.String()
+	}
+
+	stateToken, err := s.keyManager.Sign(stateClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return &models.OIDCAuthorization{
+		URL:        discovery.AuthorizationEndpoint + separator + query.Encode(),
+		StateToken: stateToken,
+	}, nil
+}
+
+// OIDCCallback exchanges the authorization code, verifies the ID token and
+// logs in the linked user, creating one with the default role if needed.
+func (s *AuthService) OIDCCallback(ctx context.Context, providerName string, code string, state string, stateToken string, client models.ClientInfo) (*models.LoginResult, error) {
+	provider, ok := s.oidc[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	token, err := s.keyManager.Parse(stateToken)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != models.OIDCPurposeState || claims["provider"] != providerName {
+		return nil, ErrInvalidState
+	}
+
+	expectedState, _ := claims["state"].(string)
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+	if expectedState == "" || subtle.ConstantTimeCompare([]byte(expectedState), []byte(state)) != 1 {
+		return nil, ErrInvalidState
+	}
+
+	if code == "" {
+		return nil, errors.New("authorization code is missing")
+	}
+
+	idToken, err := provider.exchange(ctx, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := provider.verifyIDToken(ctx, idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if link, ok := claims["link"].(string); ok {
+		userId, err := uuid.Parse(link)
+		if err != nil {
+			return nil, ErrInvalidState
+		}
+		if err := s.repository.LinkOIDCIdentity(ctx, userId, providerName, identity); err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{Linked: true}, nil
+	}
+
+	user, err := s.repository.FindOrCreateOIDCUser(ctx, providerName, identity, func(user *models.User) error {
+		return s.canLinkByEmail(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
+
+// canLinkByEmail refuses to link a login to an account that is worth taking
+// over only because the identity provider vouches for its email
+func (s *AuthService) canLinkByEmail(ctx context.Context, user *models.User) error {
+	if user.IsProtected || user.IsServiceAccount {
+		return models.ErrIdentityLinkRefused
+	}
+
+	isAdmin, err := s.adminPolicy.IsAdmin(ctx, user.RoleID)
+	if err != nil {
+		return err
+	}
+	if isAdmin {
+		return models.ErrIdentityLinkRefused
+	}
+	return nil
+}
+
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveredAt) < oidcDiscoveryTTL {
+		return p.discovery, nil
+	}
+
+	endpoint := strings.TrimSuffix(p.config.IssuerURL, "/") + "/.well-known/openid-configuration"
+
+	discovery := &oidcDiscovery{}
+	if err := p.getJSON(ctx, endpoint, discovery); err != nil {
+		return nil, fmt.Errorf("failed to load provider configuration: %v", err)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("provider configuration is incomplete")
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(p.config.IssuerURL, "/") {
+		return nil, errors.New("provider issuer does not match the configured issuer")
+	}
+
+	p.discovery = discovery
+	p.discoveredAt = time.Now()
+
+	return discovery, nil
+}
+
+func (p *oidcProvider) exchange(ctx context.Context, code string, verifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", verifier)
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token exchange failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("token exchange failed: %v", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token exchange failed with status %d", res.StatusCode)
+	}
+
+	var payload struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.IDToken == "" {
+		return "", errors.New("token response did not contain an id_token")
+	}
+
+	return payload.IDToken, nil
+}
+
+func (p *oidcProvider) verifyIDToken(ctx context.Context, idToken string, nonce string) (*models.OIDCClaims, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, discovery.JWKSURI, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid id token claims")
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	identity := &models.OIDCClaims{Subject: subject}
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+
+	// Some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	return identity, nil
+}
+
+// key returns the provider key for kid, refetching the JWKS when it is unknown
+func (p *oidcProvider) key(ctx context.Context, jwksURI string, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(p.keysFetchedAt) < oidcKeysCooldown {
+		return nil, ErrUnknownKey
+	}
+
+	set := &models.JSONWebKeySet{}
+	if err := p.getJSON(ctx, jwksURI, set); err != nil {
+		return nil, fmt.Errorf("failed to load provider keys: %v", err)
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := utils.PublicKeyFromJWK(jwk.Kty, jwk.Crv, jwk.N, jwk.E, jwk.X, jwk.Y)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, endpoint string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", res.StatusCode, endpoint)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(dest)
+}
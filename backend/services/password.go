@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/DestaAri1/RentAuto/config"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPasswordReused = errors.New("the password was used recently, please choose a different one")
+	ErrPasswordCommon = errors.New("the password is too common, please choose a different one")
+)
+
+type PasswordService struct {
+	repository models.PasswordRepository
+	config     config.PasswordPolicyConfig
+}
+
+func (s *PasswordService) Check(ctx context.Context, userId uuid.UUID, password string, related ...string) error {
+	policy := s.config
+
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("password must be at least %d characters", policy.MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char) || unicode.IsSpace(char):
+			hasSymbol = true
+		}
+	}
+
+	var missing []string
+	if policy.RequireUpper && !hasUpper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if policy.RequireLower && !hasLower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		missing = append(missing, "a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		missing = append(missing, "a symbol")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("password must contain %s", strings.Join(missing, ", "))
+	}
+
+	if utils.IsCommonPassword(password) {
+		return ErrPasswordCommon
+	}
+
+	lowered := strings.ToLower(password)
+	for _, value := range related {
+		// Use the local part of emails, the domain alone is not personal
+		value = strings.ToLower(strings.TrimSpace(strings.SplitN(value, "@", 2)[0]))
+		if len(value) >= 3 && strings.Contains(lowered, value) {
+			return errors.New("password must not contain your name or email")
+		}
+	}
+
+	if userId == uuid.Nil || policy.HistorySize == 0 {
+		return nil
+	}
+
+	history, err := s.repository.GetPasswordHistory(ctx, userId, policy.HistorySize)
+	if err != nil {
+		return err
+	}
+
+	for _, previous := range history {
+		if ok, _ := utils.VerifyPassword(password, previous.Hash); ok {
+			return ErrPasswordReused
+		}
+	}
+
+	return nil
+}
+
+func (s *PasswordService) Hash(password string) (string, error) {
+	return utils.HashPassword(password)
+}
+
+// Remember adds a newly set hash to the user's password history
+func (s *PasswordService) Remember(ctx context.Context, userId uuid.UUID, hash string) error {
+	if s.config.HistorySize == 0 {
+		return nil
+	}
+	return s.repository.AddPasswordHistory(ctx, userId, hash, s.config.HistorySize)
+}
+
+// Verify checks the password and upgrades outdated hashes, such as bcrypt, to argon2id
+func (s *PasswordService) Verify(ctx context.Context, user *models.User, password string) bool {
+	ok, needsRehash := utils.VerifyPassword(password, user.Password)
+	if !ok {
+		return false
+	}
+
+	if needsRehash {
+		hash, err := utils.HashPassword(password)
+		if err == nil {
+			err = s.repository.UpdatePasswordHash(ctx, user.ID, hash)
+		}
+		if err != nil {
+			// The login still succeeds, the upgrade is retried next time
+			log.Printf("Failed to upgrade password hash for user %s: %v", user.ID, err)
+		} else {
+			user.Password = hash
+		}
+	}
+
+	return true
+}
+
+// SetPassword checks the policy, then stores the new hash and records it in the history
+func (s *PasswordService) SetPassword(ctx context.Context, user *models.User, password string) error {
+	// The current password may predate the history, so compare it directly
+	if s.config.HistorySize > 0 {
+		if same, _ := utils.VerifyPassword(password, user.Password); same {
+			return ErrPasswordReused
+		}
+	}
+
+	if err := s.Check(ctx, user.ID, password, user.Email, user.Name); err != nil {
+		return err
+	}
+
+	hash, err := s.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.UpdatePasswordHash(ctx, user.ID, hash); err != nil {
+		return err
+	}
+
+	user.Password = hash
+
+	return s.Remember(ctx, user.ID, hash)
+}
+
+func NewPasswordService(repository models.PasswordRepository, config config.PasswordPolicyConfig) *PasswordService {
+	return &PasswordService{
+		repository: repository,
+		config:     config,
+	}
+}
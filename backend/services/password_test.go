@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DestaAri1/RentAuto/config"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/utils"
+	"github.com/google/uuid"
+)
+
+// passwordHistoryStub returns the same history for every user
+type passwordHistoryStub struct {
+	history []*models.PasswordHistory
+}
+
+func (r *passwordHistoryStub) GetPasswordHistory(ctx context.Context, userId uuid.UUID, limit int) ([]*models.PasswordHistory, error) {
+	if len(r.history) > limit {
+		return r.history[:limit], nil
+	}
+	return r.history, nil
+}
+
+func (r *passwordHistoryStub) AddPasswordHistory(ctx context.Context, userId uuid.UUID, hash string, keep int) error {
+	return nil
+}
+
+func (r *passwordHistoryStub) UpdatePasswordHash(ctx context.Context, userId uuid.UUID, hash string) error {
+	return nil
+}
+
+func TestPasswordCheck(t *testing.T) {
+	previous, err := utils.HashPassword("Previous-Pass42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository := &passwordHistoryStub{history: []*models.PasswordHistory{{Hash: previous}}}
+
+	strict := config.PasswordPolicyConfig{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		HistorySize:   5,
+	}
+	lenient := config.PasswordPolicyConfig{MinLength: 8}
+
+	userId := uuid.New()
+
+	tests := []struct {
+		name     string
+		policy   config.PasswordPolicyConfig
+		userId   uuid.UUID
+		password string
+		related  []string
+		wantErr  error
+		wantText string
+	}{
+		{name: "valid", policy: strict, password: "Tr4vel-Light!"},
+		{name: "too short", policy: strict, password: "Sh0rt-Pw", wantText: "at least 10 characters"},
+		{name: "length counts runes", policy: lenient, password: "ééééé1", wantText: "at least 8 characters"},
+		{name: "missing upper", policy: strict, password: "tr4vel-light!", wantText: "an uppercase letter"},
+		{name: "missing lower", policy: strict, password: "TR4VEL-LIGHT!", wantText: "a lowercase letter"},
+		{name: "missing digit", policy: strict, password: "Travel-Light!", wantText: "a digit"},
+		{name: "missing symbol", policy: strict, password: "Tr4velLight2", wantText: "a symbol"},
+		{name: "every missing class is listed", policy: strict, password: "travellight", wantText: "an uppercase letter, a digit, a symbol"},
+		{name: "space counts as symbol", policy: strict, password: "Tr4vel Light"},
+		{name: "classes not required", policy: lenient, password: "travellight"},
+		{name: "common password", policy: lenient, password: "Password1234", wantErr: ErrPasswordCommon},
+		{name: "contains the name", policy: lenient, password: "xxalicexx99", related: []string{"Alice"}, wantText: "your name or email"},
+		{name: "contains the email local part", policy: lenient, password: "my-jsmith-pass", related: []string{"jsmith@example.com"}, wantText: "your name or email"},
+		{name: "email domain is not personal", policy: lenient, password: "example-pass-77", related: []string{"jsmith@example.com"}},
+		{name: "short related values are ignored", policy: lenient, password: "bobsled-runner", related: []string{"Bo"}},
+		{name: "reused password", policy: strict, userId: userId, password: "Previous-Pass42", wantErr: ErrPasswordReused},
+		{name: "history skipped for new users", policy: strict, password: "Previous-Pass42"},
+		{name: "history disabled", policy: lenient, userId: userId, password: "Previous-Pass42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewPasswordService(repository, tt.policy)
+			err := service.Check(context.Background(), tt.userId, tt.password, tt.related...)
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantText != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantText) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantText)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
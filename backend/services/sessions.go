@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/utils"
+	"github.com/google/uuid"
+)
+
+// sessionRetention keeps revoked and expired sessions visible for a while before cleanup
+const sessionRetention = 7 * 24 * time.Hour
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionService struct {
+	repository models.SessionRepository
+}
+
+func (s *SessionService) CreateSession(ctx context.Context, userId uuid.UUID, client models.ClientInfo, expiresAt time.Time) (*models.Session, error) {
+	session := newSession(userId, client, expiresAt)
+
+	if err := s.repository.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// CreateImpersonationSession opens a session of userId for the support user
+// impersonatorId, it shows up in the user's session list like any other
+func (s *SessionService) CreateImpersonationSession(ctx context.Context, userId uuid.UUID, impersonatorId uuid.UUID, client models.ClientInfo, expiresAt time.Time) (*models.Session, error) {
+	session := newSession(userId, client, expiresAt)
+	session.ImpersonatorID = &impersonatorId
+
+	if err := s.repository.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// ListSessions returns the active sessions of a user and flags the one making the request
+func (s *SessionService) ListSessions(ctx context.Context, userId uuid.UUID, currentId uuid.UUID) ([]*models.Session, error) {
+	sessions, err := s.repository.GetActiveSessions(ctx, userId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentId
+	}
+
+	return sessions, nil
+}
+
+func (s *SessionService) RevokeSession(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID) error {
+	revoked, err := s.repository.RevokeSession(ctx, userId, sessionId, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if revoked == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *SessionService) RevokeOtherSessions(ctx context.Context, userId uuid.UUID, currentId uuid.UUID) (int64, error) {
+	return s.repository.RevokeUserSessions(ctx, userId, currentId, time.Now())
+}
+
+// RevokeAllSessions logs the user out everywhere
+func (s *SessionService) RevokeAllSessions(ctx context.Context, userId uuid.UUID) (int64, error) {
+	return s.repository.RevokeUserSessions(ctx, userId, uuid.Nil, time.Now())
+}
+
+// StartCleanup periodically removes sessions that expired or were revoked
+// longer ago than the retention period
+func (s *SessionService) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.repository.DeleteExpiredSessions(ctx, time.Now().Add(-sessionRetention)); err != nil {
+					log.Printf("Failed to clean up sessions: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func newSession(userId uuid.UUID, client models.ClientInfo, expiresAt time.Time) *models.Session {
+	return &models.Session{
+		UserID:     userId,
+		Device:     utils.DeviceName(client.UserAgent),
+		UserAgent:  truncate(client.UserAgent, 512),
+		IPAddress:  client.IPAddress,
+		LastSeenAt: time.Now(),
+		ExpiresAt:  expiresAt,
+	}
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
+
+func NewSessionService(repository models.SessionRepository) *SessionService {
+	return &SessionService{
+		repository: repository,
+	}
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DestaAri1/RentAuto/config"
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/google/uuid"
+)
+
+// SettingsSchema declares every runtime setting. The upload defaults come from
+// the configuration file so existing deployments keep their limits until an
+// admin changes them.
+func SettingsSchema(upload config.UploadConfig) []*models.SettingDefinition {
+	return []*models.SettingDefinition{
+		{
+			Key:         models.SettingHoldDuration,
+			Type:        models.SettingDuration,
+			Default:     15 * time.Minute,
+			Description: "How long a car is held for an unpaid order, between 1m and 24h",
+			Validate:    durationBetween(time.Minute, 24*time.Hour),
+		},
+		{
+			Key:         models.SettingLateFeeRate,
+			Type:        models.SettingFloat,
+			Default:     0.1,
+			Description: "Late fee per started hour as a fraction of the daily price, between 0 and 1",
+			Validate:    floatBetween(0, 1),
+		},
+		{
+			Key:         models.SettingTaxRate,
+			Type:        models.SettingFloat,
+			Default:     0.11,
+			Description: "Tax added to every order as a fraction of the price, between 0 and 1",
+			Validate:    floatBetween(0, 1),
+		},
+		{
+			Key:         models.SettingMinDriverAge,
+			Type:        models.SettingInt,
+			Default:     int64(21),
+			Description: "Minimum age of the driver on the first rental day, between 17 and 99",
+			Validate:    intBetween(17, 99),
+		},
+		{
+			Key:         models.SettingLicenceClasses,
+			Type:        models.SettingStringList,
+			Default:     []string{"A", "B1", "B2"},
+			Description: "Driving licence classes that allow renting a car",
+			Validate:    notEmptyList,
+		},
+		{
+			Key:         models.SettingUploadMaxFileSize,
+			Type:        models.SettingInt,
+			Default:     upload.MaxFileSize,
+			Description: "Largest accepted upload in bytes, between 1KB and 50MB",
+			Validate:    intBetween(1024, 50*1024*1024),
+		},
+		{
+			Key:         models.SettingUploadAllowedMimeTypes,
+			Type:        models.SettingStringList,
+			Default:     upload.AllowedMimeTypes,
+			Description: "Content types accepted for image uploads",
+			Validate:    notEmptyList,
+		},
+	}
+}
+
+type settingValue struct {
+	value     interface{}
+	updatedBy *uuid.UUID
+	updatedAt *time.Time
+}
+
+// SettingsService keeps the settings in memory, reads never touch the
+// database. Updates are written through and announced to the subscribers.
+type SettingsService struct {
+	repository  models.SettingRepository
+	definitions map[string]*models.SettingDefinition
+
+	mu          sync.RWMutex
+	values      map[string]*settingValue
+	subscribers map[string][]func(value interface{})
+}
+
+func (s *SettingsService) ListSettings() []*models.SettingResponse {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := make([]*models.SettingResponse, 0, len(s.definitions))
+	for key := range s.definitions {
+		settings = append(settings, s.response(key))
+	}
+
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Key < settings[j].Key
+	})
+
+	return settings
+}
+
+func (s *SettingsService) GetSetting(key string) (*models.SettingResponse, error) {
+	if _, ok := s.definitions[key]; !ok {
+		return nil, models.ErrUnknownSetting
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.response(key), nil
+}
+
+// UpdateSetting validates the value against the schema, stores it and then
+// notifies the subscribers of the setting
+func (s *SettingsService) UpdateSetting(ctx context.Context, key string, value interface{}, updatedBy uuid.UUID) (*models.SettingResponse, error) {
+	definition, ok := s.definitions[key]
+	if !ok {
+		return nil, models.ErrUnknownSetting
+	}
+
+	parsed, err := parseSetting(definition, value)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(externalSetting(parsed))
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	previous := externalSetting(s.values[key].value)
+	now := time.Now()
+	setting := &models.Setting{
+		Key:       key,
+		Value:     string(encoded),
+		UpdatedBy: &updatedBy,
+		UpdatedAt: now,
+	}
+	if err := s.repository.SaveSetting(ctx, setting, previous); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	s.values[key] = &settingValue{value: parsed, updatedBy: &updatedBy, updatedAt: &now}
+	subscribers := append([]func(value interface{}){}, s.subscribers[key]...)
+	response := s.response(key)
+	s.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(parsed)
+	}
+
+	return response, nil
+}
+
+func (s *SettingsService) Subscribe(key string, fn func(value interface{})) {
+	if _, ok := s.definitions[key]; !ok {
+		panic(fmt.Sprintf("Subscribe: unknown setting %q", key))
+	}
+
+	s.mu.Lock()
+	s.subscribers[key] = append(s.subscribers[key], fn)
+	current := s.values[key].value
+	s.mu.Unlock()
+
+	fn(current)
+}
+
+func (s *SettingsService) Int(key string) int64 {
+	value, _ := s.get(key).(int64)
+	return value
+}
+
+func (s *SettingsService) Float(key string) float64 {
+	value, _ := s.get(key).(float64)
+	return value
+}
+
+func (s *SettingsService) Bool(key string) bool {
+	value, _ := s.get(key).(bool)
+	return value
+}
+
+func (s *SettingsService) String(key string) string {
+	value, _ := s.get(key).(string)
+	return value
+}
+
+func (s *SettingsService) Duration(key string) time.Duration {
+	value, _ := s.get(key).(time.Duration)
+	return value
+}
+
+func (s *SettingsService) Strings(key string) []string {
+	value, _ := s.get(key).([]string)
+	return append([]string{}, value...)
+}
+
+func (s *SettingsService) get(key string) interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if current, ok := s.values[key]; ok {
+		return current.value
+	}
+	return nil
+}
+
+// response must be called with the lock held
+func (s *SettingsService) response(key string) *models.SettingResponse {
+	definition := s.definitions[key]
+	current := s.values[key]
+
+	return &models.SettingResponse{
+		Key:         key,
+		Type:        definition.Type,
+		Value:       externalSetting(current.value),
+		Default:     externalSetting(definition.Default),
+		Description: definition.Description,
+		UpdatedBy:   current.updatedBy,
+		UpdatedAt:   current.updatedAt,
+	}
+}
+
+// load reads the stored settings. A stored value the schema no longer
+// accepts is logged and replaced by the default instead of stopping startup.
+func (s *SettingsService) load(ctx context.Context) error {
+	stored, err := s.repository.GetSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	for key, definition := range s.definitions {
+		s.values[key] = &settingValue{value: definition.Default}
+	}
+
+	for _, setting := range stored {
+		definition, ok := s.definitions[setting.Key]
+		if !ok {
+			continue
+		}
+
+		var raw interface{}
+		if err := json.Unmarshal([]byte(setting.Value), &raw); err != nil {
+			log.Printf("Ignoring stored setting %s: %v", setting.Key, err)
+			continue
+		}
+
+		parsed, err := parseSetting(definition, raw)
+		if err != nil {
+			log.Printf("Ignoring stored setting %s: %v", setting.Key, err)
+			continue
+		}
+
+		updatedAt := setting.UpdatedAt
+		s.values[setting.Key] = &settingValue{value: parsed, updatedBy: setting.UpdatedBy, updatedAt: &updatedAt}
+	}
+
+	return nil
+}
+
+// parseSetting converts a decoded JSON value to the type of the setting and
+// validates it
+func parseSetting(definition *models.SettingDefinition, raw interface{}) (interface{}, error) {
+	var parsed interface{}
+
+	switch definition.Type {
+	case models.SettingInt:
+		number, ok := raw.(float64)
+		if !ok || number != math.Trunc(number) {
+			return nil, fmt.Errorf("%s must be a whole number", definition.Key)
+		}
+		parsed = int64(number)
+	case models.SettingFloat:
+		number, ok := raw.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a number", definition.Key)
+		}
+		parsed = number
+	case models.SettingBool:
+		flag, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must be true or false", definition.Key)
+		}
+		parsed = flag
+	case models.SettingString:
+		text, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", definition.Key)
+		}
+		parsed = text
+	case models.SettingDuration:
+		text, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a duration such as \"15m\"", definition.Key)
+		}
+		duration, err := time.ParseDuration(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a duration such as \"15m\"", definition.Key)
+		}
+		parsed = duration
+	case models.SettingStringList:
+		items, ok := raw.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of strings", definition.Key)
+		}
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings", definition.Key)
+			}
+			list = append(list, text)
+		}
+		parsed = list
+	default:
+		return nil, fmt.Errorf("%s has unsupported type %s", definition.Key, definition.Type)
+	}
+
+	if definition.Validate != nil {
+		if err := definition.Validate(parsed); err != nil {
+			return nil, fmt.Errorf("%s %v", definition.Key, err)
+		}
+	}
+
+	return parsed, nil
+}
+
+// externalSetting is the JSON form of a parsed value, durations are written
+// as strings like "15m0s"
+func externalSetting(value interface{}) interface{} {
+	if duration, ok := value.(time.Duration); ok {
+		return duration.String()
+	}
+	return value
+}
+
+func intBetween(min int64, max int64) func(interface{}) error {
+	return func(value interface{}) error {
+		if number := value.(int64); number < min || number > max {
+			return fmt.Errorf("must be between %d and %d", min, max)
+		}
+		return nil
+	}
+}
+
+func floatBetween(min float64, max float64) func(interface{}) error {
+	return func(value interface{}) error {
+		if number := value.(float64); number < min || number > max {
+			return fmt.Errorf("must be between %g and %g", min, max)
+		}
+		return nil
+	}
+}
+
+func durationBetween(min time.Duration, max time.Duration) func(interface{}) error {
+	return func(value interface{}) error {
+		if duration := value.(time.Duration); duration < min || duration > max {
+			return fmt.Errorf("must be between %s and %s", min, max)
+		}
+		return nil
+	}
+}
+
+func notEmptyList(value interface{}) error {
+	if len(value.([]string)) == 0 {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
+
+// NewSettingsService loads the stored settings over the defaults of schema
+func NewSettingsService(ctx context.Context, repository models.SettingRepository, schema []*models.SettingDefinition) (*SettingsService, error) {
+	service := &SettingsService{
+		repository:  repository,
+		definitions: map[string]*models.SettingDefinition{},
+		values:      map[string]*settingValue{},
+		subscribers: map[string][]func(value interface{}){},
+	}
+
+	for _, definition := range schema {
+		service.definitions[definition.Key] = definition
+	}
+
+	if err := service.load(ctx); err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
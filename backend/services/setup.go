@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/DestaAri1/RentAuto/models"
+	"github.com/DestaAri1/RentAuto/utils"
+	"github.com/google/uuid"
+)
+
+// SetupService creates the first super administrator. The setup token only
+// exists in memory and in the log of the instance that printed it, every
+// restart before setup completes prints a new one.
+type SetupService struct {
+	repository models.SetupRepository
+	passwords  models.PasswordServices
+
+	mu    sync.Mutex
+	token string
+}
+
+func (s *SetupService) Required(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == "" {
+		return false, nil
+	}
+	return s.repository.IsSetupRequired(ctx)
+}
+
+func (s *SetupService) Complete(ctx context.Context, formData *models.SetupForm) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == "" {
+		return nil, models.ErrSetupCompleted
+	}
+	if subtle.ConstantTimeCompare([]byte(s.token), []byte(strings.TrimSpace(formData.Token))) != 1 {
+		return nil, models.ErrInvalidSetupToken
+	}
+
+	if err := s.passwords.Check(ctx, uuid.Nil, formData.Password, formData.Email, formData.Name); err != nil {
+		return nil, err
+	}
+
+	hash, err := s.passwords.Hash(formData.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Name:     strings.TrimSpace(formData.Name),
+		Email:    strings.TrimSpace(formData.Email),
+		Password: hash,
+	}
+	if err := s.repository.CompleteSetup(ctx, user); err != nil {
+		return nil, err
+	}
+	s.token = ""
+
+	if err := s.passwords.Remember(ctx, user.ID, hash); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Setup completed, %s is the super administrator", user.Email)
+	return user, nil
+}
+
+// NewSetupService prints a setup token when the database has no super
+// administrator yet, otherwise setup stays disabled
+func NewSetupService(ctx context.Context, repository models.SetupRepository, passwords models.PasswordServices) (*SetupService, error) {
+	service := &SetupService{
+		repository: repository,
+		passwords:  passwords,
+	}
+
+	required, err := repository.IsSetupRequired(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !required {
+		return service, nil
+	}
+
+	service.token, err = utils.RandomURLToken(32)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("No super administrator exists yet. Create one with POST /api/setup and the setup token %s", service.token)
+
+	return service, nil
+}
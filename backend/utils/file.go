@@ -6,16 +6,70 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
 
 	"github.com/google/uuid"
 )
 
-const (
-	MaxFileSize = 5 * 1024 * 1024 // 5MB
-	AllowedMimeTypes = "image/jpeg,image/png,image/jpg"
+// CarAssetsDir is where car images are stored, set at startup by ConfigureAssets
+var CarAssetsDir = "assets/car"
+
+// Upload limits, kept current by the settings service through SetMaxFileSize
+// and SetAllowedMimeTypes
+var (
+	uploadMu         sync.RWMutex
+	maxFileSize      int64 = 5 * 1024 * 1024 // 5MB
+	allowedMimeTypes       = []string{"image/jpeg", "image/png", "image/jpg"}
 )
 
+// ConfigureAssets applies the configured asset folders
+func ConfigureAssets(carAssetsDir string) {
+	CarAssetsDir = carAssetsDir
+}
+
+// SetMaxFileSize changes the largest accepted upload in bytes
+func SetMaxFileSize(size int64) {
+	uploadMu.Lock()
+	defer uploadMu.Unlock()
+	maxFileSize = size
+}
+
+// SetAllowedMimeTypes changes the content types accepted for uploads
+func SetAllowedMimeTypes(mimeTypes []string) {
+	uploadMu.Lock()
+	defer uploadMu.Unlock()
+	allowedMimeTypes = append([]string{}, mimeTypes...)
+}
+
+// MaxFileSize is the largest accepted upload in bytes
+func MaxFileSize() int64 {
+	uploadMu.RLock()
+	defer uploadMu.RUnlock()
+	return maxFileSize
+}
+
+// MaxFileSizeLabel formats MaxFileSize for error messages, e.g. "5MB"
+func MaxFileSizeLabel() string {
+	size := MaxFileSize()
+	if size%(1024*1024) == 0 {
+		return fmt.Sprintf("%dMB", size/(1024*1024))
+	}
+	return fmt.Sprintf("%dKB", size/1024)
+}
+
+// IsAllowedMimeType reports whether the content type is one of the allowed upload types
+func IsAllowedMimeType(contentType string) bool {
+	uploadMu.RLock()
+	defer uploadMu.RUnlock()
+
+	for _, allowed := range allowedMimeTypes {
+		if contentType != "" && contentType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
 func SaveUploadedFile(file *multipart.FileHeader, uploadDir string) (string, error) {
 	// Create directory if it doesn't exist
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
@@ -23,13 +77,13 @@ func SaveUploadedFile(file *multipart.FileHeader, uploadDir string) (string, err
 	}
 
 	// Validate file size
-	if file.Size > MaxFileSize {
-		return "", fmt.Errorf("file size exceeds maximum limit of 5MB")
+	if file.Size > MaxFileSize() {
+		return "", fmt.Errorf("file size exceeds maximum limit of %s", MaxFileSizeLabel())
 	}
 
 	// Validate file type
 	contentType := file.Header.Get("Content-Type")
-	if !strings.Contains(AllowedMimeTypes, contentType) {
+	if !IsAllowedMimeType(contentType) {
 		return "", fmt.Errorf("invalid file type. Allowed types: jpg, jpeg, png")
 	}
 
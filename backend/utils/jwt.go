@@ -1,37 +1,206 @@
 package utils
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
+	"math/big"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJWT(claims jwt.MapClaims, signingMethod jwt.SigningMethod, secret string) (string, error) {
-	token := jwt.NewWithClaims(signingMethod, claims)
+const rsaKeyBits = 2048
 
-	// Sign the token
-	tokenString, err := token.SignedString([]byte(secret))
+// SigningMethod maps an algorithm name to its jwt signing method
+func SigningMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case jwt.SigningMethodHS256.Alg():
+		return jwt.SigningMethodHS256, nil
+	case jwt.SigningMethodRS256.Alg():
+		return jwt.SigningMethodRS256, nil
+	case jwt.SigningMethodEdDSA.Alg():
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+}
+
+// GenerateSigningKey creates new key material for the algorithm. Asymmetric keys
+// are returned as PKCS#8 / PKIX PEM, HMAC secrets as base64 with no public part.
+func GenerateSigningKey(alg string) (privateKey string, publicKey string, err error) {
+	switch alg {
+	case jwt.SigningMethodHS256.Alg():
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return "", "", fmt.Errorf("failed to generate secret: %v", err)
+		}
+		return base64.StdEncoding.EncodeToString(secret), "", nil
+
+	case jwt.SigningMethodRS256.Alg():
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate RSA key: %v", err)
+		}
+		return encodeKeyPair(key, &key.PublicKey)
+
+	case jwt.SigningMethodEdDSA.Alg():
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate Ed25519 key: %v", err)
+		}
+		return encodeKeyPair(private, public)
+
+	default:
+		return "", "", fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+}
+
+// ParseSigningKey decodes stored key material into the sign and verify keys
+// expected by the jwt library for the algorithm.
+func ParseSigningKey(alg string, privateKey string) (signKey interface{}, verifyKey interface{}, err error) {
+	if alg == jwt.SigningMethodHS256.Alg() {
+		secret, err := base64.StdEncoding.DecodeString(privateKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid HMAC secret: %v", err)
+		}
+		return secret, secret, nil
+	}
+
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, nil, fmt.Errorf("invalid PEM private key")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %v", err)
+		return nil, nil, fmt.Errorf("invalid private key: %v", err)
 	}
 
-	return tokenString, nil
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg != jwt.SigningMethodRS256.Alg() {
+			return nil, nil, fmt.Errorf("RSA key cannot be used for %s", alg)
+		}
+		return key, &key.PublicKey, nil
+	case ed25519.PrivateKey:
+		if alg != jwt.SigningMethodEdDSA.Alg() {
+			return nil, nil, fmt.Errorf("Ed25519 key cannot be used for %s", alg)
+		}
+		return key, key.Public(), nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
 }
 
-func ValidateToken(tokenString string, secret string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// PublicJWKParams returns the JWK fields describing a public verify key
+func PublicJWKParams(verifyKey interface{}) (map[string]string, bool) {
+	switch key := verifyKey.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(key),
+		}, true
+	default:
+		// Symmetric keys are never published
+		return nil, false
+	}
+}
+
+// PublicKeyFromJWK rebuilds a verify key from the JWK fields of an external issuer
+func PublicKeyFromJWK(kty, crv, n, e, x, y string) (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch kty {
+	case "RSA":
+		modulus, err := decode(n)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %v", err)
+		}
+		exponent, err := decode(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %v", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", crv)
+		}
+		xBytes, err := decode(x)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %v", err)
+		}
+		yBytes, err := decode(y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %v", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(xBytes),
+			Y:     new(big.Int).SetBytes(yBytes),
+		}, nil
+
+	case "OKP":
+		if crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %s", crv)
 		}
-		return []byte(secret), nil
-	})
+		key, err := decode(x)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(key), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", kty)
+	}
+}
+
+// RandomURLToken returns n random bytes encoded as unpadded base64url
+func RandomURLToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
 
+func encodeKeyPair(private interface{}, public interface{}) (string, string, error) {
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
 	if err != nil {
-		return nil, err
+		return "", "", fmt.Errorf("failed to encode private key: %v", err)
 	}
 
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode public key: %v", err)
 	}
 
-	return token, nil
-} 
\ No newline at end of file
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	return string(privatePEM), string(publicPEM), nil
+}
@@ -18,6 +18,40 @@ func (v *UserValidator) HandleFieldError(field string, tag string, param string)
 		return v.handlePasswordValidation(tag, param)
 	case "Role":
 		return v.handleRoleValidation(tag, param)
+	case "Branch":
+		return v.handleBranchValidation(tag, param)
+	case "Permission":
+		return v.handlePermissionValidation(tag, param)
+	case "Effect":
+		return v.handleEffectValidation(tag, param)
+	case "Token":
+		return v.handleTokenValidation(tag, param)
+	case "Status":
+		return v.handleStatusValidation(tag, param)
+	case "Reason":
+		return v.handleReasonValidation(tag, param)
+	case "Phone":
+		return v.handlePhoneValidation(tag, param)
+	case "IDNumber":
+		return v.handleIDNumberValidation(tag, param)
+	case "Address":
+		return v.handleProfileValidation("Address", tag, param)
+	case "DateOfBirth":
+		return v.handleProfileValidation("Date of birth", tag, param)
+	case "LicenceNumber":
+		return v.handleProfileValidation("Licence number", tag, param)
+	case "LicenceClass":
+		return v.handleProfileValidation("Licence class", tag, param)
+	case "LicenceExpiresAt":
+		return v.handleProfileValidation("Licence expiry", tag, param)
+	case "EmergencyContactName":
+		return v.handleProfileValidation("Emergency contact name", tag, param)
+	case "EmergencyContactPhone":
+		return v.handleProfileValidation("Emergency contact phone", tag, param)
+	case "EmergencyContactRelation":
+		return v.handleProfileValidation("Emergency contact relation", tag, param)
+	case "Note":
+		return v.handleProfileValidation("Note", tag, param)
 	default:
 		return ""
 	}
@@ -58,4 +92,84 @@ func (v *UserValidator) handleRoleValidation(tag string, param string) string {
 	default :
 		return ""
 	}
+}
+func (v *UserValidator) handleBranchValidation(tag string, param string) string {
+	switch tag {
+	case "max" :
+		return "Branch must be at most " + param + " characters"
+	default :
+		return ""
+	}
+}
+func (v *UserValidator) handlePermissionValidation(tag string, param string) string {
+	switch tag {
+	case "required" :
+		return "Permission is required"
+	default :
+		return ""
+	}
+}
+func (v *UserValidator) handleEffectValidation(tag string, param string) string {
+	switch tag {
+	case "required" :
+		return "Effect is required"
+	case "oneof" :
+		return "Effect must be one of: " + param
+	default :
+		return ""
+	}
+}
+func (v *UserValidator) handleTokenValidation(tag string, param string) string {
+	switch tag {
+	case "required" :
+		return "Token is required"
+	default :
+		return ""
+	}
+}
+func (v *UserValidator) handleStatusValidation(tag string, param string) string {
+	switch tag {
+	case "required" :
+		return "Status is required"
+	case "oneof" :
+		return "Status must be one of: " + param
+	default :
+		return ""
+	}
+}
+func (v *UserValidator) handleReasonValidation(tag string, param string) string {
+	switch tag {
+	case "required", "required_unless" :
+		return "Reason is required"
+	case "max" :
+		return "Reason must be at most " + param + " characters"
+	default :
+		return ""
+	}
+}
+func (v *UserValidator) handlePhoneValidation(tag string, param string) string {
+	switch tag {
+	case "max" :
+		return "Phone must be at most " + param + " characters"
+	default :
+		return ""
+	}
+}
+func (v *UserValidator) handleIDNumberValidation(tag string, param string) string {
+	switch tag {
+	case "max" :
+		return "ID number must be at most " + param + " characters"
+	default :
+		return ""
+	}
+}
+func (v *UserValidator) handleProfileValidation(label string, tag string, param string) string {
+	switch tag {
+	case "max" :
+		return label + " must be at most " + param + " characters"
+	case "datetime" :
+		return label + " must be a date like " + param
+	default :
+		return ""
+	}
 }
\ No newline at end of file
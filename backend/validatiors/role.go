@@ -17,6 +17,8 @@ func (v *RoleValidator) HandleFieldError(field string, tag string, param string)
 		return v.handleNameValidation(tag, param)
 	case "Permission":
 		return v.handlePermissionValidation(tag, param)
+	case "Parents":
+		return v.handleParentsValidation(tag, param)
 	default:
 		return ""
 	}
@@ -44,4 +46,13 @@ func (v *RoleValidator) handlePermissionValidation(tag string, param string) str
 	default:
 		return ""
 	}
+}
+
+func (v *RoleValidator) handleParentsValidation(tag string, param string) string {
+	switch tag {
+	case "required":
+		return "Parent roles must be valid role IDs"
+	default:
+		return ""
+	}
 }
\ No newline at end of file
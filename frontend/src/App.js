@@ -2,6 +2,7 @@ import "./App.css";
 import { BrowserRouter, Route, Routes } from "react-router-dom";
 import Login from "./pages/Auth/Login.tsx";
 import Register from "./pages/Auth/Register.tsx";
+import AcceptInvitation from "./pages/Auth/AcceptInvitation.tsx";
 import Dashboard from "./pages/Dashboard/Dashboard.tsx";
 import CarsIndex from "./pages/Dashboard/Cars.tsx";
 import Bookings from "./pages/Dashboard/Bookings.tsx";
@@ -28,6 +29,7 @@ function App() {
           {/* Public Routes */}
           <Route path="/" element={<Home />} />
           <Route path="/rent-a-car" element={<Rent />} />
+          <Route path="/invitations/accept" element={<AcceptInvitation />} />
 
           {/* Auth Routes - redirect to home if already logged in */}
           <Route
@@ -30,6 +30,12 @@ const AuthTitle = () => {
       title2: "create a new account",
       link: "/register",
     };
+  } else if (currentLocation === "/invitations/accept") {
+    authTitleItem = {
+      title1: "Choose your password",
+      title2: "sign in to your account",
+      link: "/login",
+    };
   } else {
     authTitleItem = {
       title1: "Register your account",
@@ -0,0 +1,190 @@
+import { Eye, EyeOff, Lock } from "lucide-react";
+import React, { useState } from "react";
+import { Link, useNavigate, useSearchParams } from "react-router-dom";
+import { acceptInvitation } from "../../../services/AuthServices.tsx";
+
+interface AcceptInvitationFormValues {
+  password: string;
+  confirmPassword: string;
+}
+
+const AcceptInvitationForm: React.FC = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get("token") ?? "";
+  const navigate = useNavigate();
+
+  const [formData, setFormData] = useState<AcceptInvitationFormValues>({
+    password: "",
+    confirmPassword: "",
+  });
+
+  const [errors, setErrors] = useState<{
+    password?: { message?: string };
+    confirmPassword?: { message?: string };
+  }>({});
+
+  const [showPassword, setShowPassword] = useState(false);
+  const [isLoading, setIsLoading] = useState(false);
+
+  const handleChange = (e: React.ChangeEvent<HTMLInputElement>) => {
+    const { id, value } = e.target;
+    setFormData((prev) => ({
+      ...prev,
+      [id]: value,
+    }));
+
+    // Clear error when field is modified
+    if (errors[id as keyof typeof errors]) {
+      setErrors((prev) => ({
+        ...prev,
+        [id]: undefined,
+      }));
+    }
+  };
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+
+    // Validate form
+    const newErrors: typeof errors = {};
+
+    if (!formData.password) {
+      newErrors.password = { message: "Password is required" };
+    }
+
+    if (formData.confirmPassword !== formData.password) {
+      newErrors.confirmPassword = { message: "Passwords do not match" };
+    }
+
+    if (Object.keys(newErrors).length > 0) {
+      setErrors(newErrors);
+      return;
+    }
+
+    // Submit form
+    setIsLoading(true);
+    try {
+      await acceptInvitation({ token, password: formData.password });
+
+      // The invitation is used up, sign in with the new password
+      navigate("/login");
+    } catch (error: any) {
+      // Rejected passwords can be retried with the same link
+      setErrors({
+        password: {
+          message:
+            error.response?.data?.message ??
+            "Could not set the password. Please try again.",
+        },
+      });
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  const getErrorMessage = (error: any) => {
+    return error?.message ?? "";
+  };
+
+  if (!token) {
+    return (
+      <div className="space-y-6">
+        <p className="text-sm text-gray-700">
+          This invitation link is incomplete. Open the link from your email
+          again or ask an administrator to resend it.
+        </p>
+        <Link
+          to="/login"
+          className="font-medium text-blue-600 hover:text-blue-500"
+        >
+          Back to sign in
+        </Link>
+      </div>
+    );
+  }
+
+  return (
+    <form className="space-y-6" onSubmit={handleSubmit}>
+      <div>
+        <label
+          htmlFor="password"
+          className="block text-sm font-medium text-gray-700"
+        >
+          New password
+        </label>
+        <div className="mt-1 relative rounded-md shadow-sm">
+          <div className="absolute inset-y-0 left-0 pl-3 flex items-center pointer-events-none">
+            <Lock className="h-5 w-5 text-gray-400" />
+          </div>
+          <input
+            id="password"
+            value={formData.password}
+            onChange={handleChange}
+            type={showPassword ? "text" : "password"}
+            autoComplete="new-password"
+            className="pl-10 block w-full pr-10 py-2 border border-gray-300 rounded-md shadow-sm placeholder-gray-400 focus:outline-none focus:ring-blue-500 focus:border-blue-500"
+            placeholder="••••••••"
+          />
+          <div className="absolute inset-y-0 right-0 pr-3 flex items-center">
+            <button
+              type="button"
+              onClick={() => setShowPassword(!showPassword)}
+              className="text-gray-400 hover:text-gray-500 focus:outline-none"
+            >
+              {showPassword ? (
+                <EyeOff className="h-5 w-5" />
+              ) : (
+                <Eye className="h-5 w-5" />
+              )}
+            </button>
+          </div>
+          {getErrorMessage(errors.password) && (
+            <p className="absolute text-red-700 text-sm mt-1 left-0 pl-10">
+              {getErrorMessage(errors.password)}
+            </p>
+          )}
+        </div>
+      </div>
+
+      <div>
+        <label
+          htmlFor="confirmPassword"
+          className="block text-sm font-medium text-gray-700"
+        >
+          Confirm password
+        </label>
+        <div className="mt-1 relative rounded-md shadow-sm">
+          <div className="absolute inset-y-0 left-0 pl-3 flex items-center pointer-events-none">
+            <Lock className="h-5 w-5 text-gray-400" />
+          </div>
+          <input
+            id="confirmPassword"
+            value={formData.confirmPassword}
+            onChange={handleChange}
+            type={showPassword ? "text" : "password"}
+            autoComplete="new-password"
+            className="pl-10 block w-full pr-3 py-2 border border-gray-300 rounded-md shadow-sm placeholder-gray-400 focus:outline-none focus:ring-blue-500 focus:border-blue-500"
+            placeholder="••••••••"
+          />
+          {getErrorMessage(errors.confirmPassword) && (
+            <p className="absolute text-red-700 text-sm mt-1 left-0 pl-10">
+              {getErrorMessage(errors.confirmPassword)}
+            </p>
+          )}
+        </div>
+      </div>
+
+      <div>
+        <button
+          type="submit"
+          disabled={isLoading}
+          className="w-full flex justify-center py-2 px-4 border border-transparent rounded-md shadow-sm text-sm font-medium text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 disabled:bg-blue-300 disabled:cursor-not-allowed"
+        >
+          {isLoading ? "Saving..." : "Set password"}
+        </button>
+      </div>
+    </form>
+  );
+};
+
+export default AcceptInvitationForm;
@@ -3,7 +3,7 @@ import { BaseForm } from "../../../types/form";
 import { UserFormData } from "../../../schema/Schema.tsx";
 import { FieldErrors, UseFormRegister } from "react-hook-form";
 import InputField from "../../ui/InputField.tsx";
-import { UserPen, Mail, Lock, Shield } from "lucide-react";
+import { UserPen, Mail, Shield } from "lucide-react";
 import SelectField from "../../ui/SelectField.tsx";
 import { Role } from "../../../types/index.tsx";
 
@@ -52,16 +52,6 @@ export default function FormUser({
         icon={<Mail className="w-5 h-5 text-gray-500" />}
       />
 
-      <InputField
-        label="Password"
-        name="password"
-        type="password"
-        placeholder="Enter Password"
-        register={register}
-        error={errors.password}
-        icon={<Lock className="w-5 h-5 text-gray-500" />}
-      />
-
       <SelectField
         data={role}
         icon={<Shield className="w-5 h-5 text-gray-500" />}
@@ -41,7 +41,7 @@ export default function UserProvider({ children }: ChildProps) {
 
     try {
       const response = await GetAllUsers();
-      const data = response.data.data || [];
+      const data = response.data.data?.items || [];
       setUsers(data);
       hasFetched.current = true;
     } catch (error) {
@@ -18,7 +18,6 @@ export default function useUserForm() {
     defaultValues: {
       username: "",
       email: "",
-      password: "",
       role_id: "",
     },
     mode: "onChange",
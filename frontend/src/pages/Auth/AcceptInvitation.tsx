@@ -0,0 +1,14 @@
+import React from 'react'
+import AcceptInvitationForm from '../../components/Auth/Invitation/AcceptInvitationForm.tsx';
+import AuthLayout from '../../layout/AuthLayout.tsx';
+import { AuthFormBox } from '../../components/Auth/Auth.tsx';
+
+export default function AcceptInvitation() {
+    return (
+    <AuthLayout title="Choose your password">
+      <AuthFormBox>
+        <AcceptInvitationForm />
+      </AuthFormBox>
+    </AuthLayout>
+  );
+};
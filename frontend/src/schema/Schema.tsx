@@ -138,13 +138,6 @@ export const userFormSchema = z.object({
     .string()
     .min(1, { message: "This field has to be filled." })
     .email("This is not a valid email."),
-  password: z
-    .union([
-      z.string().min(8, "At least password should be 8 char"),
-      z.literal("").transform(() => undefined), // Empty string jadi undefined
-      z.undefined()
-    ])
-    .optional(),
   role_id: uuidSchema({ field: "role" })
 });
 
@@ -25,6 +25,14 @@ export const register = async (data: AuthInput): Promise<AuthResponse> => {
   return response.data;
 };
 
+// Accept invitation (also used for password resets sent by an admin)
+export const acceptInvitation = async (data: {
+  token: string;
+  password: string;
+}): Promise<void> => {
+  await apiClient.post("auth/invitations/accept", data);
+};
+
 // Logout
 export const logout = async (): Promise<void> => {
   try {